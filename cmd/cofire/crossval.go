@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/lovoo/cofire"
+)
+
+func runCrossVal(args []string) error {
+	var (
+		fs        = flag.NewFlagSet("crossval", flag.ExitOnError)
+		folds     = fs.Int("folds", 5, "number of folds")
+		seed      = fs.Int64("seed", 1, "random seed")
+		params    = cofire.DefaultParams()
+		k         = fs.Int("k", 0, "number of top products for ranking metrics, 0 to skip them")
+		threshold = fs.Float64("threshold", 0, "minimum score of relevant products for ranking metrics")
+	)
+	input, format := datasetFlags(fs)
+	paramFlags(fs, &params)
+	fs.Parse(args)
+	if *input == "" {
+		return fmt.Errorf("missing -input")
+	}
+
+	ratings, err := readRatings(*input, *format)
+	if err != nil {
+		return err
+	}
+	cv := &cofire.CrossValidator{
+		Folds:  *folds,
+		Params: params,
+		Seed:   *seed,
+	}
+	if *k > 0 {
+		cv.Ranking = &cofire.RankingEvaluator{K: *k, Threshold: *threshold}
+	}
+	res, err := cv.Run(ratings)
+	if err != nil {
+		return err
+	}
+	fmt.Println(res)
+	return nil
+}
+
+// paramFlags defines flags for the learner parameters in fs.
+func paramFlags(fs *flag.FlagSet, params *cofire.Parameters) {
+	fs.IntVar(&params.Rank, "rank", params.Rank, "number of latent features")
+	fs.Float64Var(&params.Gamma, "gamma", params.Gamma, "SGD gamma parameter")
+	fs.Float64Var(&params.Lambda, "lambda", params.Lambda, "SGD lambda parameter")
+	fs.IntVar(&params.Iterations, "iterations", params.Iterations, "number of iterations")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/cofire/ratings"
+)
+
+// datasetFlags defines the flags of a ratings file in fs.
+func datasetFlags(fs *flag.FlagSet) (input, format *string) {
+	input = fs.String("input", "", "input ratings file")
+	format = fs.String("format", "", "format of the input: csv, tsv, movielens, jsonl or netflix (default by file extension)")
+	return input, format
+}
+
+// readRatings reads all ratings of a file.
+func readRatings(fname, format string) ([]cofire.Rating, error) {
+	return ratings.ReadFile(fname, ratings.Format(format))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/goka"
+)
+
+func runExport(args []string) error {
+	var (
+		fs         = flag.NewFlagSet("export", flag.ExitOnError)
+		brokers    = fs.String("brokers", "localhost:9092", "comma separated Kafka brokers to recover the table from")
+		group      = fs.String("group", "cofire", "cofire group of the learner")
+		storage    = fs.String("storage", "", "local goka storage directory to read the table from instead of Kafka")
+		output     = fs.String("output", "model", "prefix of the output files <prefix>.<u|p>.<format>")
+		format     = fs.String("format", formatJSONL, "output format: jsonl, csv, npy or fvecs")
+		side       = fs.String("side", "both", "features to export: u, p or both")
+		minUpdates = fs.Uint64("min-updates", 0, "minimum number of updates of exported features")
+		prefix     = fs.String("prefix", "", "only export keys with this prefix")
+		codecName  = tableCodecFlag(fs)
+	)
+	fs.Parse(args)
+	if *side != "u" && *side != "p" && *side != "both" {
+		return fmt.Errorf("invalid side %q", *side)
+	}
+	codec, err := tableCodec(*codecName)
+	if err != nil {
+		return err
+	}
+
+	var model cofire.Model
+	if *storage != "" {
+		m, err := openStorage(*storage, goka.Group(*group), codec)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		model = m
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		view, done, err := recoverView(ctx, strings.Split(*brokers, ","), goka.Group(*group), codec)
+		if err != nil {
+			cancel()
+			return err
+		}
+		defer func() {
+			cancel()
+			<-done
+		}()
+		model = cofire.NewViewModel(view)
+	}
+
+	var us, ps []row
+	keep := func(f *cofire.Features) bool {
+		return f != nil && f.Updates >= *minUpdates
+	}
+	err = model.Range(func(key string, e *cofire.Entry) bool {
+		if !strings.HasPrefix(key, *prefix) {
+			return true
+		}
+		if *side != "p" && keep(e.U) {
+			us = append(us, newRow(key, e.U))
+		}
+		if *side != "u" && keep(e.P) {
+			ps = append(ps, newRow(key, e.P))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if *side != "p" {
+		if err := writeMatrix(*output, "u", *format, us); err != nil {
+			return err
+		}
+	}
+	if *side != "u" {
+		if err := writeMatrix(*output, "p", *format, ps); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("exported %d U and %d P features\n", len(us), len(ps))
+	return nil
+}
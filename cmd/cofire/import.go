@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/goka"
+)
+
+func runImport(args []string) error {
+	var (
+		fs          = flag.NewFlagSet("import", flag.ExitOnError)
+		brokers     = fs.String("brokers", "localhost:9092", "comma separated Kafka brokers")
+		group       = fs.String("group", "cofire", "cofire group of the learner")
+		input       = fs.String("input", "model", "prefix of the input files <prefix>.<u|p>.<format>")
+		format      = fs.String("format", formatJSONL, "input format: jsonl, csv, npy or fvecs")
+		side        = fs.String("side", "both", "features to import: u, p or both")
+		rank        = fs.Int("rank", cofire.DefaultParams().Rank, "rank of the learner's features; rows of other ranks are rejected")
+		rate        = fs.Float64("rate", 1000, "maximum number of updates emitted per second, 0 for no limit")
+		verify      = fs.Bool("verify", true, "verify the imported features through a view of the table")
+		timeout     = fs.Duration("timeout", time.Minute, "maximum time to wait for the learner to apply the updates")
+		precision   = fs.String("precision", "float64", "precision of the learner's table: float64, float32 or int8")
+		tolerance   = fs.Float64("tolerance", 0, "maximum difference of verified factors relative to the largest factor, 0 for the rounding error of -precision")
+		codecName   = tableCodecFlag(fs)
+		updateCodec = fs.String("update-codec", "", "codec of the learner's update topic: proto or json, by default the table's -codec")
+	)
+	fs.Parse(args)
+	if *side != "u" && *side != "p" && *side != "both" {
+		return fmt.Errorf("invalid side %q", *side)
+	}
+	codec, err := tableCodec(*codecName)
+	if err != nil {
+		return err
+	}
+	if *updateCodec == "" {
+		*updateCodec = *codecName
+	}
+	emitCodec, err := updatesCodec(*updateCodec)
+	if err != nil {
+		return err
+	}
+	p, err := cofire.PrecisionOf(*precision)
+	if err != nil {
+		return err
+	}
+	if *tolerance <= 0 {
+		*tolerance = precisionTolerance(p)
+	}
+
+	var us, ps []row
+	if *side != "p" {
+		rows, err := readMatrix(*input, "u", *format)
+		if err != nil {
+			return err
+		}
+		us = rows
+	}
+	if *side != "u" {
+		rows, err := readMatrix(*input, "p", *format)
+		if err != nil {
+			return err
+		}
+		ps = rows
+	}
+	for side, rows := range map[string][]row{"u": us, "p": ps} {
+		if err := checkRank(rows, *rank); err != nil {
+			name, _ := matrixFiles(*input, side, *format)
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	var (
+		bs      = strings.Split(*brokers, ",")
+		updates = goka.Stream(fmt.Sprintf("%s-update", *group))
+	)
+	emitter, err := goka.NewEmitter(bs, updates, emitCodec)
+	if err != nil {
+		return err
+	}
+	var tick <-chan time.Time
+	if interval := rateInterval(*rate); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	emit := func(key string, u *cofire.Update) error {
+		if tick != nil {
+			<-tick
+		}
+		return emitter.EmitSync(key, u)
+	}
+	for _, r := range us {
+		if err := emit(r.Key, &cofire.Update{U: r.features()}); err != nil {
+			return err
+		}
+	}
+	for _, r := range ps {
+		if err := emit(r.Key, &cofire.Update{P: r.features()}); err != nil {
+			return err
+		}
+	}
+	emitter.Finish()
+	fmt.Printf("emitted %d U and %d P updates to %s\n", len(us), len(ps), updates)
+
+	if !*verify {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	view, done, err := recoverView(ctx, bs, goka.Group(*group), codec)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cancel()
+		<-done
+	}()
+	return verifyImport(ctx, cofire.NewViewModel(view), us, ps, *tolerance)
+}
+
+// rateInterval returns the interval between updates emitted at rate per
+// second, or 0 for no limit if rate is not positive or above 1e9.
+func rateInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// updatesCodec returns the codec of the update topic named by the
+// -update-codec flag.
+func updatesCodec(name string) (goka.Codec, error) {
+	switch name {
+	case "proto":
+		return new(cofire.UpdateCodec), nil
+	case "json":
+		return new(cofire.JSONUpdateCodec), nil
+	}
+	return nil, fmt.Errorf("unknown codec %q", name)
+}
+
+// precisionTolerance returns the tolerance of verifying features stored with
+// precision p, ie, one quantization step for Int8.
+func precisionTolerance(p cofire.Precision) float64 {
+	if p == cofire.Int8 {
+		return 1.0 / math.MaxInt8
+	}
+	return 1e-6
+}
+
+// checkRank returns an error if a row does not have rank factors.
+func checkRank(rows []row, rank int) error {
+	for _, r := range rows {
+		if len(r.V) != rank {
+			return fmt.Errorf("%s has rank %d, expected %d", r.Key, len(r.V), rank)
+		}
+	}
+	return nil
+}
+
+// features returns the features of the row.
+func (r *row) features() *cofire.Features {
+	return &cofire.Features{V: r.V, Bias: r.Bias, Updates: r.Updates}
+}
+
+// verifyImport checks that the model contains the imported features until
+// all match or ctx is done.
+func verifyImport(ctx context.Context, m cofire.Model, us, ps []row, tolerance float64) error {
+	for {
+		missing, err := mismatches(m, us, ps, tolerance)
+		if err != nil {
+			return err
+		}
+		if missing == 0 {
+			fmt.Printf("verified %d U and %d P features\n", len(us), len(ps))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d of %d features do not match the table", missing, len(us)+len(ps))
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// mismatches counts the rows whose features differ in the model.
+func mismatches(m cofire.Model, us, ps []row, tolerance float64) (int, error) {
+	var n int
+	for _, side := range []struct {
+		rows []row
+		get  func(e *cofire.Entry) *cofire.Features
+	}{
+		{us, (*cofire.Entry).GetU},
+		{ps, (*cofire.Entry).GetP},
+	} {
+		for _, r := range side.rows {
+			e, err := m.Get(r.Key)
+			if err != nil {
+				return 0, err
+			}
+			if !r.matches(side.get(e), tolerance) {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// matches returns whether f has the features of the row up to a tolerance
+// relative to the largest factor of the row, but at least 1.
+func (r *row) matches(f *cofire.Features, tolerance float64) bool {
+	if f == nil || len(f.V) != len(r.V) {
+		return false
+	}
+	scale := 1.0
+	for _, v := range r.V {
+		scale = math.Max(scale, math.Abs(v))
+	}
+	if math.Abs(f.Bias-r.Bias) > tolerance*math.Max(1, math.Abs(r.Bias)) {
+		return false
+	}
+	for i := range r.V {
+		if math.Abs(f.V[i]-r.V[i]) > tolerance*scale {
+			return false
+		}
+	}
+	return true
+}
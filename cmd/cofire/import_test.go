@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/goka"
+)
+
+func TestRateInterval(t *testing.T) {
+	for rate, expected := range map[float64]time.Duration{
+		1000: time.Millisecond,
+		0:    0,
+		-1:   0,
+		1e10: 0,
+	} {
+		if interval := rateInterval(rate); interval != expected {
+			t.Errorf("rate %g: expected interval %v, got %v", rate, expected, interval)
+		}
+	}
+}
+
+func TestCheckRank(t *testing.T) {
+	rows := []row{{Key: "a", V: []float64{1, 2}}, {Key: "b", V: []float64{1, 2, 3}}}
+	if err := checkRank(rows[:1], 2); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkRank(rows, 2); err == nil {
+		t.Errorf("expected error for row of rank 3")
+	}
+}
+
+func TestRow_Matches(t *testing.T) {
+	var (
+		r = row{Key: "a", V: []float64{-2, 0.5, 0.01}, Bias: 0.3}
+		f = &cofire.Features{V: []float64{-2, 0.5, 0.01}, Bias: 0.3, Updates: 7}
+	)
+	for _, p := range []cofire.Precision{cofire.Float64, cofire.Float32, cofire.Int8} {
+		// features read back from a table of precision p
+		b, err := (&cofire.EntryCodec{Precision: p}).Encode(&cofire.Entry{U: f})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := new(cofire.EntryCodec).Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u := e.(*cofire.Entry).U; !r.matches(u, precisionTolerance(p)) {
+			t.Errorf("%v: %v does not match %v", p, u.V, r.V)
+		}
+	}
+	if r.matches(&cofire.Features{V: []float64{-2, 0.6, 0.01}, Bias: 0.3}, precisionTolerance(cofire.Int8)) {
+		t.Errorf("features differing by more than a quantization step match")
+	}
+}
+
+func TestUpdatesCodec(t *testing.T) {
+	for name, expected := range map[string]goka.Codec{
+		"proto": new(cofire.UpdateCodec),
+		"json":  new(cofire.JSONUpdateCodec),
+	} {
+		c, err := updatesCodec(name)
+		if err != nil || reflect.TypeOf(c) != reflect.TypeOf(expected) {
+			t.Errorf("%s: unexpected codec %T, %v", name, c, err)
+		}
+	}
+	if _, err := updatesCodec("xml"); err == nil {
+		t.Errorf("expected error for unknown codec")
+	}
+}
@@ -0,0 +1,62 @@
+// Command cofire provides offline tools for cofire models.
+//
+// Usage:
+//
+//	cofire <command> [flags]
+//
+// The commands are:
+//
+//	crossval    k-fold cross-validation of the learner's parameters
+//	tune        hyperparameter search with cross-validation
+//	export      export the learned features to files
+//	import      import features from files via the update stream
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command runs a subcommand with its arguments.
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"crossval": {runCrossVal, "k-fold cross-validation of the learner's parameters"},
+	"tune":     {runTune, "hyperparameter search with cross-validation"},
+	"export":   {runExport, "export the learned features to files"},
+	"import":   {runImport, "import features from files via the update stream"},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cofire: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cofire %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: cofire <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nThe commands are:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\t%-10s  %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/lovoo/cofire"
+)
+
+// row is the feature vector of a user or product.
+type row struct {
+	Key     string    `json:"key"`
+	V       []float64 `json:"v"`
+	Bias    float64   `json:"bias"`
+	Updates uint64    `json:"updates,omitempty"`
+}
+
+func newRow(key string, f *cofire.Features) row {
+	return row{Key: key, V: f.V, Bias: f.Bias, Updates: f.Updates}
+}
+
+// Matrix formats. The npy and fvecs formats store the bias as the last
+// column and the keys in a separate index file with one key per line.
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatNPY   = "npy"
+	formatFvecs = "fvecs"
+)
+
+// matrixFiles returns the file of a side and, for formats without keys, the
+// file of its key index.
+func matrixFiles(prefix, side, format string) (string, string) {
+	name := fmt.Sprintf("%s.%s.%s", prefix, side, format)
+	if format == formatNPY || format == formatFvecs {
+		return name, fmt.Sprintf("%s.%s.ids", prefix, side)
+	}
+	return name, ""
+}
+
+// writeMatrix writes the rows of a side in a format.
+func writeMatrix(prefix, side, format string, rows []row) error {
+	name, index := matrixFiles(prefix, side, format)
+	if index != "" {
+		if err := writeFile(index, func(w io.Writer) error { return writeIndex(w, rows) }); err != nil {
+			return err
+		}
+	}
+	return writeFile(name, func(w io.Writer) error {
+		switch format {
+		case formatJSONL:
+			return writeJSONL(w, rows)
+		case formatCSV:
+			return writeCSV(w, rows)
+		case formatNPY:
+			return writeNPY(w, rows)
+		case formatFvecs:
+			return writeFvecs(w, rows)
+		}
+		return fmt.Errorf("unknown format %q", format)
+	})
+}
+
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rank returns the common rank of the rows.
+func rank(rows []row) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	r := len(rows[0].V)
+	for _, row := range rows {
+		if len(row.V) != r {
+			return 0, fmt.Errorf("rank of %s is %d, expected %d", row.Key, len(row.V), r)
+		}
+	}
+	return r, nil
+}
+
+func writeIndex(w io.Writer, rows []row) error {
+	for _, r := range rows {
+		if _, err := fmt.Fprintln(w, r.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSONL(w io.Writer, rows []row) error {
+	enc := json.NewEncoder(w)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes key,updates,bias,v0,...,vn rows.
+func writeCSV(w io.Writer, rows []row) error {
+	cw := csv.NewWriter(w)
+	for _, r := range rows {
+		rec := []string{r.Key, strconv.FormatUint(r.Updates, 10), strconv.FormatFloat(r.Bias, 'g', -1, 64)}
+		for _, v := range r.V {
+			rec = append(rec, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeNPY writes a NumPy array of float64 with shape (rows, rank+1).
+func writeNPY(w io.Writer, rows []row) error {
+	r, err := rank(rows)
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", len(rows), r+1)
+	// pad header, so that the data is aligned to 64 bytes
+	pad := 64 - (10+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	for i := 0; i < pad; i++ {
+		header += " "
+	}
+	header += "\n"
+
+	if _, err := io.WriteString(w, "\x93NUMPY\x01\x00"); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(header))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	buf := make([]byte, 8*(r+1))
+	for _, row := range rows {
+		for i, v := range append(row.V[:len(row.V):len(row.V)], row.Bias) {
+			binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFvecs writes each row as its dimension rank+1 followed by the float32
+// values.
+func writeFvecs(w io.Writer, rows []row) error {
+	r, err := rank(rows)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4+4*(r+1))
+	binary.LittleEndian.PutUint32(buf, uint32(r+1))
+	for _, row := range rows {
+		for i, v := range append(row.V[:len(row.V):len(row.V)], row.Bias) {
+			binary.LittleEndian.PutUint32(buf[4+4*i:], math.Float32bits(float32(v)))
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMatrix reads the rows of a side in a format.
+func readMatrix(prefix, side, format string) ([]row, error) {
+	var (
+		name, index = matrixFiles(prefix, side, format)
+		keys        []string
+		rows        []row
+	)
+	if index != "" {
+		err := readFile(index, func(r io.Reader) (err error) {
+			keys, err = readIndex(r)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := readFile(name, func(r io.Reader) (err error) {
+		switch format {
+		case formatJSONL:
+			rows, err = readJSONL(r)
+		case formatCSV:
+			rows, err = readCSV(r)
+		case formatNPY:
+			rows, err = readNPY(r, keys)
+		case formatFvecs:
+			rows, err = readFvecs(r, keys)
+		default:
+			err = fmt.Errorf("unknown format %q", format)
+		}
+		return err
+	})
+	return rows, err
+}
+
+func readFile(name string, read func(r io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := read(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func readIndex(r io.Reader) ([]string, error) {
+	var (
+		keys []string
+		s    = bufio.NewScanner(r)
+	)
+	for s.Scan() {
+		keys = append(keys, s.Text())
+	}
+	return keys, s.Err()
+}
+
+func readJSONL(r io.Reader) ([]row, error) {
+	var (
+		rows []row
+		dec  = json.NewDecoder(r)
+	)
+	dec.DisallowUnknownFields()
+	for {
+		var row row
+		err := dec.Decode(&row)
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readCSV(r io.Reader) ([]row, error) {
+	var (
+		rows []row
+		cr   = csv.NewReader(r)
+	)
+	cr.FieldsPerRecord = -1
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("expected at least 3 columns, got %d", len(rec))
+		}
+		row := row{Key: rec[0], V: make([]float64, len(rec)-3)}
+		if row.Updates, err = strconv.ParseUint(rec[1], 10, 64); err != nil {
+			return nil, err
+		}
+		if row.Bias, err = strconv.ParseFloat(rec[2], 64); err != nil {
+			return nil, err
+		}
+		for i, s := range rec[3:] {
+			if row.V[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+var npyShape = regexp.MustCompile(`'shape': \((\d+), (\d+)\)`)
+
+// readNPY reads a NumPy array of float64 with shape (keys, rank+1) as written
+// by writeNPY.
+func readNPY(r io.Reader, keys []string) ([]row, error) {
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(magic, []byte("\x93NUMPY")) {
+		return nil, errors.New("not a NumPy file")
+	}
+	var hlen uint16
+	if err := binary.Read(r, binary.LittleEndian, &hlen); err != nil {
+		return nil, err
+	}
+	header := make([]byte, hlen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(header, []byte("'descr': '<f8'")) || !bytes.Contains(header, []byte("'fortran_order': False")) {
+		return nil, fmt.Errorf("unsupported array: %s", bytes.TrimSpace(header))
+	}
+	m := npyShape.FindSubmatch(header)
+	if m == nil {
+		return nil, fmt.Errorf("unsupported shape: %s", bytes.TrimSpace(header))
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	cols, _ := strconv.Atoi(string(m[2]))
+	if n != len(keys) || cols < 1 {
+		return nil, fmt.Errorf("shape (%d, %d) does not match %d keys", n, cols, len(keys))
+	}
+
+	rows := make([]row, n)
+	buf := make([]byte, 8*cols)
+	for i := range rows {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		rows[i] = row{Key: keys[i], V: make([]float64, cols-1)}
+		for j := range rows[i].V {
+			rows[i].V[j] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*j:]))
+		}
+		rows[i].Bias = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*(cols-1):]))
+	}
+	return rows, nil
+}
+
+// readFvecs reads the rows of an fvecs file as written by writeFvecs.
+func readFvecs(r io.Reader, keys []string) ([]row, error) {
+	var rows []row
+	for {
+		var d uint32
+		err := binary.Read(r, binary.LittleEndian, &d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if d < 1 {
+			return nil, errors.New("invalid dimension 0")
+		}
+		if len(rows) == len(keys) {
+			return nil, fmt.Errorf("more vectors than %d keys", len(keys))
+		}
+		v := make([]float32, d)
+		if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+		row := row{Key: keys[len(rows)], V: make([]float64, d-1), Bias: float64(v[d-1])}
+		for i := range row.V {
+			row.V[i] = float64(v[i])
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) != len(keys) {
+		return nil, fmt.Errorf("%d vectors do not match %d keys", len(rows), len(keys))
+	}
+	return rows, nil
+}
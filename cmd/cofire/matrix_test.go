@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lovoo/cofire"
+)
+
+func TestMatrix_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cofire-matrix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rows := []row{
+		{Key: "a", V: []float64{0.5, -1.25}, Bias: 0.125, Updates: 3},
+		{Key: "b,c", V: []float64{2, 4}, Bias: -0.5, Updates: 1},
+	}
+	for _, format := range []string{formatJSONL, formatCSV, formatNPY, formatFvecs} {
+		prefix := filepath.Join(dir, "model")
+		if err := writeMatrix(prefix, "u", format, rows); err != nil {
+			t.Fatalf("%s: error writing: %v", format, err)
+		}
+		read, err := readMatrix(prefix, "u", format)
+		if err != nil {
+			t.Fatalf("%s: error reading: %v", format, err)
+		}
+
+		expected := rows
+		if format == formatNPY || format == formatFvecs {
+			// update counts are not stored
+			expected = []row{rows[0], rows[1]}
+			for i := range expected {
+				expected[i].Updates = 0
+			}
+		}
+		if !reflect.DeepEqual(read, expected) {
+			t.Errorf("%s: expected %v, got %v", format, expected, read)
+		}
+	}
+}
+
+func TestMatrix_Rank(t *testing.T) {
+	rows := []row{{Key: "a", V: []float64{1}}, {Key: "b", V: []float64{1, 2}}}
+	if err := writeNPY(ioutil.Discard, rows); err == nil {
+		t.Errorf("expected error writing rows of different rank")
+	}
+}
+
+func TestMismatches(t *testing.T) {
+	var (
+		us = []row{{Key: "user", V: []float64{1, 2}, Bias: 1}}
+		ps = []row{{Key: "prod", V: []float64{3, 4}}}
+		m  = cofire.MemoryModel{
+			"user": {U: &cofire.Features{V: []float64{1, 2 + 1e-9}, Bias: 1}},
+		}
+	)
+	n, err := mismatches(m, us, ps, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 mismatch, got %d", n)
+	}
+
+	m["prod"] = &cofire.Entry{P: &cofire.Features{V: []float64{3, 4}}}
+	if n, _ := mismatches(m, us, ps, 1e-6); n != 0 {
+		t.Errorf("expected no mismatch, got %d", n)
+	}
+	m["prod"].P.V[1] = 5
+	if n, _ := mismatches(m, us, ps, 1e-6); n != 1 {
+		t.Errorf("expected 1 mismatch, got %d", n)
+	}
+}
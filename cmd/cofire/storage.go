@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/goka"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// offsetKey is the key under which goka stores the partition offset.
+const offsetKey = "__offset"
+
+// tableCodecFlag defines the flag of the codec of the group table in fs.
+func tableCodecFlag(fs *flag.FlagSet) *string {
+	return fs.String("codec", "proto", "codec of the learner's table entries: proto or json")
+}
+
+// tableCodec returns the codec of the group table named by a -codec flag.
+func tableCodec(name string) (goka.Codec, error) {
+	switch name {
+	case "proto":
+		return new(cofire.EntryCodec), nil
+	case "json":
+		return new(cofire.JSONEntryCodec), nil
+	}
+	return nil, fmt.Errorf("unknown codec %q", name)
+}
+
+// storageModel is a model read from the local LevelDB storage of the
+// partitions of a group table.
+type storageModel struct {
+	dbs   []*leveldb.DB
+	codec goka.Codec
+}
+
+// openStorage opens the partitions of the group table in a goka storage
+// directory read-only. Entries are decoded with codec.
+func openStorage(dir string, group goka.Group, codec goka.Codec) (*storageModel, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s.*", goka.GroupTable(group))))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no partitions of %s in %s", goka.GroupTable(group), dir)
+	}
+	m := &storageModel{codec: codec}
+	for _, path := range paths {
+		db, err := leveldb.OpenFile(path, &opt.Options{ReadOnly: true})
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		m.dbs = append(m.dbs, db)
+	}
+	return m, nil
+}
+
+func (m *storageModel) Get(key string) (*cofire.Entry, error) {
+	for _, db := range m.dbs {
+		b, err := db.Get([]byte(key), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		e, err := m.codec.Decode(b)
+		if err != nil {
+			return nil, err
+		}
+		return e.(*cofire.Entry), nil
+	}
+	return nil, nil
+}
+
+func (m *storageModel) Range(fn func(key string, e *cofire.Entry) bool) error {
+	for _, db := range m.dbs {
+		it := db.NewIterator(nil, nil)
+		for it.Next() {
+			if string(it.Key()) == offsetKey {
+				continue
+			}
+			e, err := m.codec.Decode(it.Value())
+			if err != nil {
+				it.Release()
+				return fmt.Errorf("%s: %v", it.Key(), err)
+			}
+			if !fn(string(it.Key()), e.(*cofire.Entry)) {
+				it.Release()
+				return nil
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *storageModel) Close() error {
+	for _, db := range m.dbs {
+		db.Close()
+	}
+	return nil
+}
+
+// recoverView starts a view of the group table with codec and waits until it
+// is recovered or ctx is done.
+func recoverView(ctx context.Context, brokers []string, group goka.Group, codec goka.Codec) (*goka.View, <-chan error, error) {
+	view, err := goka.NewView(brokers, goka.GroupTable(group), codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- view.Run(ctx)
+	}()
+	for !view.Recovered() {
+		select {
+		case err := <-done:
+			if err == nil {
+				err = ctx.Err()
+			}
+			return nil, nil, fmt.Errorf("view stopped before recovery: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return view, done, nil
+}
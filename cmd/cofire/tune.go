@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/lovoo/cofire"
+)
+
+// trial is a cross-validated set of parameters.
+type trial struct {
+	params cofire.Parameters
+	res    *cofire.CrossValidation
+	err    error
+}
+
+// metrics by name and whether larger values are better.
+var tuneMetrics = map[string]struct {
+	value  func(*cofire.CrossValidation) float64
+	larger bool
+}{
+	"rmse":      {func(cv *cofire.CrossValidation) float64 { return cv.RMSE.Mean }, false},
+	"mae":       {func(cv *cofire.CrossValidation) float64 { return cv.MAE.Mean }, false},
+	"precision": {func(cv *cofire.CrossValidation) float64 { return cv.Precision.Mean }, true},
+	"recall":    {func(cv *cofire.CrossValidation) float64 { return cv.Recall.Mean }, true},
+	"ndcg":      {func(cv *cofire.CrossValidation) float64 { return cv.NDCG.Mean }, true},
+	"map":       {func(cv *cofire.CrossValidation) float64 { return cv.MAP.Mean }, true},
+	"auc":       {func(cv *cofire.CrossValidation) float64 { return cv.AUC.Mean }, true},
+}
+
+func runTune(args []string) error {
+	var (
+		fs         = flag.NewFlagSet("tune", flag.ExitOnError)
+		folds      = fs.Int("folds", 3, "number of cross-validation folds per trial")
+		seed       = fs.Int64("seed", 1, "random seed")
+		ranks      = fs.String("rank", "10", "ranks, comma separated or min:max for random search")
+		gammas     = fs.String("gamma", "0.01", "gammas, comma separated or min:max for random search")
+		lambdas    = fs.String("lambda", "0.001", "lambdas, comma separated or min:max for random search")
+		iterations = fs.String("iterations", "1", "iterations, comma separated or min:max for random search")
+		random     = fs.Int("random", 0, "number of random trials, 0 to search the grid")
+		parallel   = fs.Int("parallel", runtime.NumCPU(), "number of trials run in parallel")
+		metric     = fs.String("metric", "rmse", "metric to sort by: rmse, mae, precision, recall, ndcg, map or auc")
+		k          = fs.Int("k", 10, "number of top products for ranking metrics")
+		threshold  = fs.Float64("threshold", 0, "minimum score of relevant products for ranking metrics")
+		output     = fs.String("output", "", "file to write the results table to, stdout if empty")
+		best       = fs.String("best", "", "file to write the best parameters to as JSON")
+	)
+	input, format := datasetFlags(fs)
+	fs.Parse(args)
+	if *input == "" {
+		return fmt.Errorf("missing -input")
+	}
+	if *parallel < 1 {
+		return fmt.Errorf("invalid -parallel %d", *parallel)
+	}
+	m, ok := tuneMetrics[*metric]
+	if !ok {
+		return fmt.Errorf("unknown metric %q", *metric)
+	}
+	ranking := *metric != "rmse" && *metric != "mae"
+
+	space, err := newSearchSpace(*ranks, *gammas, *lambdas, *iterations)
+	if err != nil {
+		return err
+	}
+	var candidates []cofire.Parameters
+	if *random > 0 {
+		candidates = space.random(rand.New(rand.NewSource(*seed)), *random)
+	} else if candidates, err = space.grid(); err != nil {
+		return err
+	}
+
+	ratings, err := readRatings(*input, *format)
+	if err != nil {
+		return err
+	}
+
+	// cross-validate candidates in parallel
+	var (
+		trials = make([]trial, len(candidates))
+		next   = make(chan int)
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < *parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				cv := &cofire.CrossValidator{Folds: *folds, Params: candidates[i], Seed: *seed}
+				if ranking {
+					cv.Ranking = &cofire.RankingEvaluator{K: *k, Threshold: *threshold}
+				}
+				res, err := cv.Run(ratings)
+				trials[i] = trial{params: candidates[i], res: res, err: err}
+			}
+		}()
+	}
+	for i := range candidates {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, t := range trials {
+		if t.err != nil {
+			return fmt.Errorf("trial %+v: %v", t.params, t.err)
+		}
+	}
+	sort.SliceStable(trials, func(i, j int) bool {
+		a, b := m.value(trials[i].res), m.value(trials[j].res)
+		if m.larger {
+			return a > b
+		}
+		return a < b
+	})
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeTrials(w, trials, ranking); err != nil {
+		return err
+	}
+
+	if *best != "" && len(trials) > 0 {
+		b, err := json.MarshalIndent(trials[0].params, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(*best, append(b, '\n'), 0644)
+	}
+	return nil
+}
+
+// writeTrials writes the trials as a tab separated table.
+func writeTrials(w io.Writer, trials []trial, ranking bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "rank\tgamma\tlambda\titerations\trmse\tmae")
+	if ranking {
+		fmt.Fprint(tw, "\tprecision\trecall\tndcg\tmap\tauc")
+	}
+	fmt.Fprintln(tw)
+	for _, t := range trials {
+		p, r := t.params, t.res
+		fmt.Fprintf(tw, "%d\t%.4g\t%.4g\t%d\t%.6f\t%.6f", p.Rank, p.Gamma, p.Lambda, p.Iterations, r.RMSE.Mean, r.MAE.Mean)
+		if ranking {
+			fmt.Fprintf(tw, "\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f", r.Precision.Mean, r.Recall.Mean, r.NDCG.Mean, r.MAP.Mean, r.AUC.Mean)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// searchSpace contains the values or ranges of each parameter.
+type searchSpace struct {
+	ranks, gammas, lambdas, iterations []float64
+	ranges                             [4]bool
+}
+
+func newSearchSpace(ranks, gammas, lambdas, iterations string) (*searchSpace, error) {
+	var (
+		s   = new(searchSpace)
+		err error
+	)
+	for i, p := range []struct {
+		flag   string
+		values *[]float64
+	}{
+		{ranks, &s.ranks},
+		{gammas, &s.gammas},
+		{lambdas, &s.lambdas},
+		{iterations, &s.iterations},
+	} {
+		*p.values, s.ranges[i], err = parseValues(p.flag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseValues parses a comma separated list of values or a min:max range.
+func parseValues(s string) ([]float64, bool, error) {
+	sep, isRange := ",", strings.Contains(s, ":")
+	if isRange {
+		sep = ":"
+	}
+	var values []float64
+	for _, v := range strings.Split(s, sep) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid value %q: %v", v, err)
+		}
+		values = append(values, f)
+	}
+	if isRange && (len(values) != 2 || values[0] > values[1]) {
+		return nil, false, fmt.Errorf("invalid range %q", s)
+	}
+	return values, isRange, nil
+}
+
+// grid returns all combinations of the parameter values.
+func (s *searchSpace) grid() ([]cofire.Parameters, error) {
+	for _, r := range s.ranges {
+		if r {
+			return nil, fmt.Errorf("ranges require random search")
+		}
+	}
+	var params []cofire.Parameters
+	for _, rank := range s.ranks {
+		for _, gamma := range s.gammas {
+			for _, lambda := range s.lambdas {
+				for _, iters := range s.iterations {
+					params = append(params, cofire.Parameters{
+						Rank:       int(rank),
+						Gamma:      gamma,
+						Lambda:     lambda,
+						Iterations: int(iters),
+					})
+				}
+			}
+		}
+	}
+	return params, nil
+}
+
+// random returns n random combinations of the parameters. Values are picked
+// from lists, integers uniformly from ranges, and gamma and lambda
+// log-uniformly from ranges.
+func (s *searchSpace) random(rnd *rand.Rand, n int) []cofire.Parameters {
+	pick := func(values []float64, isRange, log bool) float64 {
+		switch {
+		case !isRange:
+			return values[rnd.Intn(len(values))]
+		case log && values[0] > 0:
+			min, max := math.Log(values[0]), math.Log(values[1])
+			return math.Exp(min + rnd.Float64()*(max-min))
+		case log:
+			return values[0] + rnd.Float64()*(values[1]-values[0])
+		default:
+			return float64(int(values[0]) + rnd.Intn(int(values[1])-int(values[0])+1))
+		}
+	}
+	params := make([]cofire.Parameters, n)
+	for i := range params {
+		params[i] = cofire.Parameters{
+			Rank:       int(pick(s.ranks, s.ranges[0], false)),
+			Gamma:      pick(s.gammas, s.ranges[1], true),
+			Lambda:     pick(s.lambdas, s.ranges[2], true),
+			Iterations: int(pick(s.iterations, s.ranges[3], false)),
+		}
+	}
+	return params
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/lovoo/cofire"
+)
+
+func TestParseValues(t *testing.T) {
+	for _, tc := range []struct {
+		s       string
+		values  []float64
+		isRange bool
+		err     bool
+	}{
+		{s: "10", values: []float64{10}},
+		{s: "5,10, 20", values: []float64{5, 10, 20}},
+		{s: "0.001:0.1", values: []float64{0.001, 0.1}, isRange: true},
+		{s: "1:1", values: []float64{1, 1}, isRange: true},
+		{s: "", err: true},
+		{s: "5,x", err: true},
+		{s: "0.1:0.001", err: true},
+		{s: "1:2:3", err: true},
+		{s: "1,2:3", err: true},
+	} {
+		values, isRange, err := parseValues(tc.s)
+		switch {
+		case tc.err && err == nil:
+			t.Errorf("%q: expected error, got %v", tc.s, values)
+		case !tc.err && err != nil:
+			t.Errorf("%q: unexpected error: %v", tc.s, err)
+		case !reflect.DeepEqual(values, tc.values) || isRange != tc.isRange:
+			t.Errorf("%q: expected %v (range %v), got %v (range %v)", tc.s, tc.values, tc.isRange, values, isRange)
+		}
+	}
+}
+
+func TestSearchSpace_Grid(t *testing.T) {
+	s, err := newSearchSpace("5,10", "0.01", "0.1,0.2", "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	params, err := s.grid()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []cofire.Parameters{
+		{Rank: 5, Gamma: 0.01, Lambda: 0.1, Iterations: 3},
+		{Rank: 5, Gamma: 0.01, Lambda: 0.2, Iterations: 3},
+		{Rank: 10, Gamma: 0.01, Lambda: 0.1, Iterations: 3},
+		{Rank: 10, Gamma: 0.01, Lambda: 0.2, Iterations: 3},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected %v, got %v", expected, params)
+	}
+
+	// ranges have no grid
+	s, err = newSearchSpace("5:10", "0.01", "0.1", "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.grid(); err == nil {
+		t.Errorf("expected error for grid of range")
+	}
+}
+
+func TestSearchSpace_Random(t *testing.T) {
+	s, err := newSearchSpace("5:10", "0.001:0.1", "0.1,0.2", "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	params := s.random(rand.New(rand.NewSource(1)), 50)
+	if len(params) != 50 {
+		t.Fatalf("expected 50 samples, got %d", len(params))
+	}
+	for _, p := range params {
+		if p.Rank < 5 || p.Rank > 10 || p.Gamma < 0.001 || p.Gamma > 0.1 ||
+			(p.Lambda != 0.1 && p.Lambda != 0.2) || p.Iterations != 3 {
+			t.Errorf("sample out of search space: %+v", p)
+		}
+	}
+
+	if _, err := newSearchSpace("5", "x", "0.1", "3"); err == nil {
+		t.Errorf("expected error for invalid gamma")
+	}
+}
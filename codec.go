@@ -15,35 +15,82 @@ func (c *RatingCodec) Decode(b []byte) (interface{}, error) {
 	return &v, proto.Unmarshal(b, &v)
 }
 
-type messageCodec struct{}
+type messageCodec struct {
+	precision Precision
+}
 
 func (c *messageCodec) Encode(v interface{}) ([]byte, error) {
+	if m, ok := v.(*Message); ok {
+		return proto.Marshal(packMessage(m, c.precision))
+	}
 	return proto.Marshal(v.(proto.Message))
 }
 
 func (c *messageCodec) Decode(b []byte) (interface{}, error) {
 	var v Message
-	return &v, proto.Unmarshal(b, &v)
+	err := proto.Unmarshal(b, &v)
+	v.F.unpack()
+	return &v, err
 }
 
-type UpdateCodec struct{}
+// UpdateCodec encodes updates with features of Precision and decodes updates
+// with features of any precision.
+type UpdateCodec struct {
+	Precision Precision
+}
 
 func (c *UpdateCodec) Encode(v interface{}) ([]byte, error) {
+	if u, ok := v.(*Update); ok {
+		return proto.Marshal(packUpdate(u, c.Precision))
+	}
 	return proto.Marshal(v.(proto.Message))
 }
 
 func (c *UpdateCodec) Decode(b []byte) (interface{}, error) {
 	var v Update
-	return &v, proto.Unmarshal(b, &v)
+	err := proto.Unmarshal(b, &v)
+	v.unpack()
+	return &v, err
 }
 
-type EntryCodec struct{}
+// EntryCodec encodes entries with features of Precision and decodes entries
+// with features of any precision.
+type EntryCodec struct {
+	Precision Precision
+}
 
 func (c *EntryCodec) Encode(v interface{}) ([]byte, error) {
+	if e, ok := v.(*Entry); ok {
+		return proto.Marshal(packEntry(e, c.Precision))
+	}
 	return proto.Marshal(v.(proto.Message))
 }
 
 func (c *EntryCodec) Decode(b []byte) (interface{}, error) {
 	var v Entry
+	err := proto.Unmarshal(b, &v)
+	v.unpack()
+	return &v, err
+}
+
+type PopularityCodec struct{}
+
+func (c *PopularityCodec) Encode(v interface{}) ([]byte, error) {
+	return proto.Marshal(v.(proto.Message))
+}
+
+func (c *PopularityCodec) Decode(b []byte) (interface{}, error) {
+	var v Popularity
+	return &v, proto.Unmarshal(b, &v)
+}
+
+type RecommendationsCodec struct{}
+
+func (c *RecommendationsCodec) Encode(v interface{}) ([]byte, error) {
+	return proto.Marshal(v.(proto.Message))
+}
+
+func (c *RecommendationsCodec) Decode(b []byte) (interface{}, error) {
+	var v Recommendations
 	return &v, proto.Unmarshal(b, &v)
 }
@@ -10,9 +10,21 @@ It is generated from these files:
 It has these top-level messages:
 	Features
 	Entry
+	Rate
+	Rated
 	Rating
 	Message
 	Update
+	PredictRequest
+	PredictResponse
+	RecommendRequest
+	RecommendResponse
+	Recommendation
+	Recommendations
+	GetEntryRequest
+	FoldInRequest
+	Popularity
+	Bucket
 */
 package cofire
 
@@ -20,6 +32,11 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
@@ -38,17 +55,21 @@ const (
 	Stage_ENTRY   Stage = 0
 	Stage_PRODUCT Stage = 1
 	Stage_USER    Stage = 2
+	// FLUSH applies the batched PRODUCT messages of a hot product.
+	Stage_FLUSH Stage = 3
 )
 
 var Stage_name = map[int32]string{
 	0: "ENTRY",
 	1: "PRODUCT",
 	2: "USER",
+	3: "FLUSH",
 }
 var Stage_value = map[string]int32{
 	"ENTRY":   0,
 	"PRODUCT": 1,
 	"USER":    2,
+	"FLUSH":   3,
 }
 
 func (x Stage) String() string {
@@ -56,10 +77,48 @@ func (x Stage) String() string {
 }
 func (Stage) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
-// Features are the factors and a bias for a user or product.
+// Source is the origin of recommendations.
+type Source int32
+
+const (
+	// PERSONALIZED recommendations are predicted with the user's U features.
+	Source_PERSONALIZED Source = 0
+	// POPULAR_RECENT recommendations are the most rated products in a recent
+	// time window.
+	Source_POPULAR_RECENT Source = 1
+	// POPULAR recommendations are the most rated products overall.
+	Source_POPULAR Source = 2
+)
+
+var Source_name = map[int32]string{
+	0: "PERSONALIZED",
+	1: "POPULAR_RECENT",
+	2: "POPULAR",
+}
+var Source_value = map[string]int32{
+	"PERSONALIZED":   0,
+	"POPULAR_RECENT": 1,
+	"POPULAR":        2,
+}
+
+func (x Source) String() string {
+	return proto.EnumName(Source_name, int32(x))
+}
+func (Source) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+// Features are the factors and a bias for a user or product. Encoded
+// features store the factors in v, v32 or q depending on the precision of the
+// codec; decoded features always have them in v.
 type Features struct {
 	V    []float64 `protobuf:"fixed64,1,rep,packed,name=v" json:"v,omitempty"`
 	Bias float64   `protobuf:"fixed64,2,opt,name=bias" json:"bias,omitempty"`
+	// number of SGD updates applied to the features.
+	Updates uint64 `protobuf:"varint,3,opt,name=updates" json:"updates,omitempty"`
+	// factors stored as float32 if v is empty.
+	V32 []float32 `protobuf:"fixed32,4,rep,packed,name=v32" json:"v32,omitempty"`
+	// factors quantized to int8 if v and v32 are empty: v[i] = int8(q[i]) * scale.
+	Q     []byte  `protobuf:"bytes,5,opt,name=q" json:"q,omitempty"`
+	Scale float64 `protobuf:"fixed64,6,opt,name=scale" json:"scale,omitempty"`
 }
 
 func (m *Features) Reset()                    { *m = Features{} }
@@ -81,10 +140,41 @@ func (m *Features) GetBias() float64 {
 	return 0
 }
 
+func (m *Features) GetUpdates() uint64 {
+	if m != nil {
+		return m.Updates
+	}
+	return 0
+}
+
+func (m *Features) GetV32() []float32 {
+	if m != nil {
+		return m.V32
+	}
+	return nil
+}
+
+func (m *Features) GetQ() []byte {
+	if m != nil {
+		return m.Q
+	}
+	return nil
+}
+
+func (m *Features) GetScale() float64 {
+	if m != nil {
+		return m.Scale
+	}
+	return 0
+}
+
 // Entry are the factors (either U or P) for a user or product.
 type Entry struct {
-	U *Features `protobuf:"bytes,1,opt,name=u" json:"u,omitempty"`
-	P *Features `protobuf:"bytes,2,opt,name=p" json:"p,omitempty"`
+	U     *Features `protobuf:"bytes,1,opt,name=u" json:"u,omitempty"`
+	P     *Features `protobuf:"bytes,2,opt,name=p" json:"p,omitempty"`
+	Rated []*Rated  `protobuf:"bytes,3,rep,name=rated" json:"rated,omitempty"`
+	// rate of PRODUCT messages of the product, if hot products are batched.
+	ProductRate *Rate `protobuf:"bytes,4,opt,name=product_rate,json=productRate" json:"product_rate,omitempty"`
 }
 
 func (m *Entry) Reset()                    { *m = Entry{} }
@@ -106,18 +196,86 @@ func (m *Entry) GetP() *Features {
 	return nil
 }
 
+func (m *Entry) GetRated() []*Rated {
+	if m != nil {
+		return m.Rated
+	}
+	return nil
+}
+
+func (m *Entry) GetProductRate() *Rate {
+	if m != nil {
+		return m.ProductRate
+	}
+	return nil
+}
+
+// Rate is an exponentially decayed rate of events per second.
+type Rate struct {
+	Value float64 `protobuf:"fixed64,1,opt,name=value" json:"value,omitempty"`
+	// time of the last event in unix milliseconds.
+	Time int64 `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *Rate) Reset()                    { *m = Rate{} }
+func (m *Rate) String() string            { return proto.CompactTextString(m) }
+func (*Rate) ProtoMessage()               {}
+func (*Rate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Rate) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *Rate) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+// Rated is a product rated by a user.
+type Rated struct {
+	ProductId string `protobuf:"bytes,1,opt,name=product_id,json=productId" json:"product_id,omitempty"`
+	// timestamp of the rating in unix milliseconds.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *Rated) Reset()                    { *m = Rated{} }
+func (m *Rated) String() string            { return proto.CompactTextString(m) }
+func (*Rated) ProtoMessage()               {}
+func (*Rated) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Rated) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *Rated) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
 // Rating represents the score that a user gives to a product.
 // Cofire Learner accepts Rating messages to factorize the rating matrix.
 type Rating struct {
 	UserId    string  `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
 	ProductId string  `protobuf:"bytes,2,opt,name=product_id,json=productId" json:"product_id,omitempty"`
 	Score     float64 `protobuf:"fixed64,3,opt,name=score" json:"score,omitempty"`
+	// timestamp of the rating in unix milliseconds, 0 if unknown.
+	Timestamp int64 `protobuf:"varint,4,opt,name=timestamp" json:"timestamp,omitempty"`
 }
 
 func (m *Rating) Reset()                    { *m = Rating{} }
 func (m *Rating) String() string            { return proto.CompactTextString(m) }
 func (*Rating) ProtoMessage()               {}
-func (*Rating) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Rating) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *Rating) GetUserId() string {
 	if m != nil {
@@ -140,18 +298,31 @@ func (m *Rating) GetScore() float64 {
 	return 0
 }
 
+func (m *Rating) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
 // Message are internal messages of the Cofire Learner.
 type Message struct {
 	Stage  Stage     `protobuf:"varint,1,opt,name=stage,enum=cofire.Stage" json:"stage,omitempty"`
 	Rating *Rating   `protobuf:"bytes,2,opt,name=rating" json:"rating,omitempty"`
 	F      *Features `protobuf:"bytes,3,opt,name=f" json:"f,omitempty"`
 	Iters  uint32    `protobuf:"varint,4,opt,name=iters" json:"iters,omitempty"`
+	// timestamp of the message that started the iteration in unix
+	// milliseconds.
+	Timestamp int64 `protobuf:"varint,5,opt,name=timestamp" json:"timestamp,omitempty"`
+	// timestamp of the rating message that entered the first iteration in
+	// unix milliseconds.
+	Entered int64 `protobuf:"varint,6,opt,name=entered" json:"entered,omitempty"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
 func (m *Message) String() string            { return proto.CompactTextString(m) }
 func (*Message) ProtoMessage()               {}
-func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *Message) GetStage() Stage {
 	if m != nil {
@@ -181,6 +352,20 @@ func (m *Message) GetIters() uint32 {
 	return 0
 }
 
+func (m *Message) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Message) GetEntered() int64 {
+	if m != nil {
+		return m.Entered
+	}
+	return 0
+}
+
 // Update messages overwrite the U or P features of in the user/product's
 // entry.
 type Update struct {
@@ -191,7 +376,7 @@ type Update struct {
 func (m *Update) Reset()                    { *m = Update{} }
 func (m *Update) String() string            { return proto.CompactTextString(m) }
 func (*Update) ProtoMessage()               {}
-func (*Update) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Update) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *Update) GetU() *Features {
 	if m != nil {
@@ -207,37 +392,547 @@ func (m *Update) GetP() *Features {
 	return nil
 }
 
+// PredictRequest asks for the predicted score of a user for a product.
+// If the user has no U features, they are folded in from ratings.
+type PredictRequest struct {
+	UserId    string    `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	ProductId string    `protobuf:"bytes,2,opt,name=product_id,json=productId" json:"product_id,omitempty"`
+	Ratings   []*Rating `protobuf:"bytes,3,rep,name=ratings" json:"ratings,omitempty"`
+}
+
+func (m *PredictRequest) Reset()                    { *m = PredictRequest{} }
+func (m *PredictRequest) String() string            { return proto.CompactTextString(m) }
+func (*PredictRequest) ProtoMessage()               {}
+func (*PredictRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *PredictRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *PredictRequest) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *PredictRequest) GetRatings() []*Rating {
+	if m != nil {
+		return m.Ratings
+	}
+	return nil
+}
+
+// PredictResponse contains the predicted score.
+type PredictResponse struct {
+	Score float64 `protobuf:"fixed64,1,opt,name=score" json:"score,omitempty"`
+}
+
+func (m *PredictResponse) Reset()                    { *m = PredictResponse{} }
+func (m *PredictResponse) String() string            { return proto.CompactTextString(m) }
+func (*PredictResponse) ProtoMessage()               {}
+func (*PredictResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *PredictResponse) GetScore() float64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+// RecommendRequest asks for the top k products of a user.
+// If the user has no U features, they are folded in from ratings.
+// Products already rated by the user are excluded unless include_rated is set.
+type RecommendRequest struct {
+	UserId       string    `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	K            uint32    `protobuf:"varint,2,opt,name=k" json:"k,omitempty"`
+	Ratings      []*Rating `protobuf:"bytes,3,rep,name=ratings" json:"ratings,omitempty"`
+	IncludeRated bool      `protobuf:"varint,4,opt,name=include_rated,json=includeRated" json:"include_rated,omitempty"`
+}
+
+func (m *RecommendRequest) Reset()                    { *m = RecommendRequest{} }
+func (m *RecommendRequest) String() string            { return proto.CompactTextString(m) }
+func (*RecommendRequest) ProtoMessage()               {}
+func (*RecommendRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *RecommendRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *RecommendRequest) GetK() uint32 {
+	if m != nil {
+		return m.K
+	}
+	return 0
+}
+
+func (m *RecommendRequest) GetRatings() []*Rating {
+	if m != nil {
+		return m.Ratings
+	}
+	return nil
+}
+
+func (m *RecommendRequest) GetIncludeRated() bool {
+	if m != nil {
+		return m.IncludeRated
+	}
+	return false
+}
+
+// RecommendResponse contains the recommended products sorted by descending
+// score.
+type RecommendResponse struct {
+	Recommendations []*Recommendation `protobuf:"bytes,1,rep,name=recommendations" json:"recommendations,omitempty"`
+	Source          Source            `protobuf:"varint,2,opt,name=source,enum=cofire.Source" json:"source,omitempty"`
+}
+
+func (m *RecommendResponse) Reset()                    { *m = RecommendResponse{} }
+func (m *RecommendResponse) String() string            { return proto.CompactTextString(m) }
+func (*RecommendResponse) ProtoMessage()               {}
+func (*RecommendResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *RecommendResponse) GetRecommendations() []*Recommendation {
+	if m != nil {
+		return m.Recommendations
+	}
+	return nil
+}
+
+func (m *RecommendResponse) GetSource() Source {
+	if m != nil {
+		return m.Source
+	}
+	return Source_PERSONALIZED
+}
+
+// Recommendation is a product with its predicted score.
+type Recommendation struct {
+	ProductId string  `protobuf:"bytes,1,opt,name=product_id,json=productId" json:"product_id,omitempty"`
+	Score     float64 `protobuf:"fixed64,2,opt,name=score" json:"score,omitempty"`
+}
+
+func (m *Recommendation) Reset()                    { *m = Recommendation{} }
+func (m *Recommendation) String() string            { return proto.CompactTextString(m) }
+func (*Recommendation) ProtoMessage()               {}
+func (*Recommendation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *Recommendation) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *Recommendation) GetScore() float64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+// Recommendations are the precomputed recommendations of a user.
+type Recommendations struct {
+	Recommendations []*Recommendation `protobuf:"bytes,1,rep,name=recommendations" json:"recommendations,omitempty"`
+	// timestamp of the computation in unix milliseconds.
+	Timestamp int64 `protobuf:"varint,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	// u are the user features used in the computation.
+	U *Features `protobuf:"bytes,3,opt,name=u" json:"u,omitempty"`
+}
+
+func (m *Recommendations) Reset()                    { *m = Recommendations{} }
+func (m *Recommendations) String() string            { return proto.CompactTextString(m) }
+func (*Recommendations) ProtoMessage()               {}
+func (*Recommendations) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *Recommendations) GetRecommendations() []*Recommendation {
+	if m != nil {
+		return m.Recommendations
+	}
+	return nil
+}
+
+func (m *Recommendations) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Recommendations) GetU() *Features {
+	if m != nil {
+		return m.U
+	}
+	return nil
+}
+
+// GetEntryRequest asks for the entry of a user or product.
+type GetEntryRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *GetEntryRequest) Reset()                    { *m = GetEntryRequest{} }
+func (m *GetEntryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetEntryRequest) ProtoMessage()               {}
+func (*GetEntryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *GetEntryRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+// FoldInRequest contains the ratings of a user to fold in.
+type FoldInRequest struct {
+	Ratings []*Rating `protobuf:"bytes,1,rep,name=ratings" json:"ratings,omitempty"`
+}
+
+func (m *FoldInRequest) Reset()                    { *m = FoldInRequest{} }
+func (m *FoldInRequest) String() string            { return proto.CompactTextString(m) }
+func (*FoldInRequest) ProtoMessage()               {}
+func (*FoldInRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *FoldInRequest) GetRatings() []*Rating {
+	if m != nil {
+		return m.Ratings
+	}
+	return nil
+}
+
+// Popularity are the rating statistics of a product.
+type Popularity struct {
+	Count   uint64    `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
+	Sum     float64   `protobuf:"fixed64,2,opt,name=sum" json:"sum,omitempty"`
+	Buckets []*Bucket `protobuf:"bytes,3,rep,name=buckets" json:"buckets,omitempty"`
+}
+
+func (m *Popularity) Reset()                    { *m = Popularity{} }
+func (m *Popularity) String() string            { return proto.CompactTextString(m) }
+func (*Popularity) ProtoMessage()               {}
+func (*Popularity) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *Popularity) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Popularity) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *Popularity) GetBuckets() []*Bucket {
+	if m != nil {
+		return m.Buckets
+	}
+	return nil
+}
+
+// Bucket counts the ratings of a product in a time slot.
+type Bucket struct {
+	// start of the time slot in unix milliseconds.
+	Start int64   `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
+	Count uint64  `protobuf:"varint,2,opt,name=count" json:"count,omitempty"`
+	Sum   float64 `protobuf:"fixed64,3,opt,name=sum" json:"sum,omitempty"`
+}
+
+func (m *Bucket) Reset()                    { *m = Bucket{} }
+func (m *Bucket) String() string            { return proto.CompactTextString(m) }
+func (*Bucket) ProtoMessage()               {}
+func (*Bucket) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *Bucket) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *Bucket) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Bucket) GetSum() float64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Features)(nil), "cofire.Features")
 	proto.RegisterType((*Entry)(nil), "cofire.Entry")
+	proto.RegisterType((*Rate)(nil), "cofire.Rate")
+	proto.RegisterType((*Rated)(nil), "cofire.Rated")
 	proto.RegisterType((*Rating)(nil), "cofire.Rating")
 	proto.RegisterType((*Message)(nil), "cofire.Message")
 	proto.RegisterType((*Update)(nil), "cofire.Update")
+	proto.RegisterType((*PredictRequest)(nil), "cofire.PredictRequest")
+	proto.RegisterType((*PredictResponse)(nil), "cofire.PredictResponse")
+	proto.RegisterType((*RecommendRequest)(nil), "cofire.RecommendRequest")
+	proto.RegisterType((*RecommendResponse)(nil), "cofire.RecommendResponse")
+	proto.RegisterType((*Recommendation)(nil), "cofire.Recommendation")
+	proto.RegisterType((*Recommendations)(nil), "cofire.Recommendations")
+	proto.RegisterType((*GetEntryRequest)(nil), "cofire.GetEntryRequest")
+	proto.RegisterType((*FoldInRequest)(nil), "cofire.FoldInRequest")
+	proto.RegisterType((*Popularity)(nil), "cofire.Popularity")
+	proto.RegisterType((*Bucket)(nil), "cofire.Bucket")
 	proto.RegisterEnum("cofire.Stage", Stage_name, Stage_value)
+	proto.RegisterEnum("cofire.Source", Source_name, Source_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Predictor service
+
+type PredictorClient interface {
+	// Predict predicts the score of a user for a product.
+	Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error)
+	// Recommend returns the products with the highest predicted scores for a
+	// user.
+	Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*RecommendResponse, error)
+	// GetEntry returns the learnt entry of a user or product.
+	GetEntry(ctx context.Context, in *GetEntryRequest, opts ...grpc.CallOption) (*Entry, error)
+	// FoldIn computes the U features of a user from a list of ratings without
+	// training the model.
+	FoldIn(ctx context.Context, in *FoldInRequest, opts ...grpc.CallOption) (*Features, error)
+}
+
+type predictorClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPredictorClient(cc *grpc.ClientConn) PredictorClient {
+	return &predictorClient{cc}
+}
+
+func (c *predictorClient) Predict(ctx context.Context, in *PredictRequest, opts ...grpc.CallOption) (*PredictResponse, error) {
+	out := new(PredictResponse)
+	err := grpc.Invoke(ctx, "/cofire.Predictor/Predict", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *predictorClient) Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*RecommendResponse, error) {
+	out := new(RecommendResponse)
+	err := grpc.Invoke(ctx, "/cofire.Predictor/Recommend", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *predictorClient) GetEntry(ctx context.Context, in *GetEntryRequest, opts ...grpc.CallOption) (*Entry, error) {
+	out := new(Entry)
+	err := grpc.Invoke(ctx, "/cofire.Predictor/GetEntry", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *predictorClient) FoldIn(ctx context.Context, in *FoldInRequest, opts ...grpc.CallOption) (*Features, error) {
+	out := new(Features)
+	err := grpc.Invoke(ctx, "/cofire.Predictor/FoldIn", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Predictor service
+
+type PredictorServer interface {
+	// Predict predicts the score of a user for a product.
+	Predict(context.Context, *PredictRequest) (*PredictResponse, error)
+	// Recommend returns the products with the highest predicted scores for a
+	// user.
+	Recommend(context.Context, *RecommendRequest) (*RecommendResponse, error)
+	// GetEntry returns the learnt entry of a user or product.
+	GetEntry(context.Context, *GetEntryRequest) (*Entry, error)
+	// FoldIn computes the U features of a user from a list of ratings without
+	// training the model.
+	FoldIn(context.Context, *FoldInRequest) (*Features, error)
+}
+
+func RegisterPredictorServer(s *grpc.Server, srv PredictorServer) {
+	s.RegisterService(&_Predictor_serviceDesc, srv)
+}
+
+func _Predictor_Predict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PredictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).Predict(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cofire.Predictor/Predict",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).Predict(ctx, req.(*PredictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Predictor_Recommend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecommendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).Recommend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cofire.Predictor/Recommend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).Recommend(ctx, req.(*RecommendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Predictor_GetEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).GetEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cofire.Predictor/GetEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).GetEntry(ctx, req.(*GetEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Predictor_FoldIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FoldInRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PredictorServer).FoldIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cofire.Predictor/FoldIn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PredictorServer).FoldIn(ctx, req.(*FoldInRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Predictor_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cofire.Predictor",
+	HandlerType: (*PredictorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Predict",
+			Handler:    _Predictor_Predict_Handler,
+		},
+		{
+			MethodName: "Recommend",
+			Handler:    _Predictor_Recommend_Handler,
+		},
+		{
+			MethodName: "GetEntry",
+			Handler:    _Predictor_GetEntry_Handler,
+		},
+		{
+			MethodName: "FoldIn",
+			Handler:    _Predictor_FoldIn_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cofire.proto",
 }
 
 func init() { proto.RegisterFile("cofire.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 306 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x91, 0xcd, 0x4e, 0xb3, 0x40,
-	0x14, 0x86, 0xbf, 0xd3, 0x96, 0x69, 0x7b, 0xfa, 0x13, 0x72, 0xf2, 0x25, 0xb2, 0xd1, 0x10, 0x4c,
-	0x0c, 0x1a, 0xd3, 0x45, 0xbd, 0x04, 0xad, 0xda, 0x85, 0x3f, 0x99, 0xb6, 0x26, 0xae, 0xcc, 0x14,
-	0x06, 0xc2, 0xa6, 0x90, 0x99, 0xa1, 0x89, 0xf7, 0xe0, 0x45, 0x9b, 0x19, 0x60, 0xd7, 0x9d, 0x3b,
-	0xde, 0xf3, 0xc0, 0xcb, 0x73, 0x66, 0x70, 0x9a, 0x94, 0x59, 0xa1, 0xe4, 0xa2, 0x52, 0xa5, 0x29,
-	0x89, 0x35, 0x29, 0xba, 0xc5, 0xd1, 0xa3, 0x14, 0xa6, 0x56, 0x52, 0xd3, 0x14, 0xe1, 0x18, 0x40,
-	0xd8, 0x8f, 0x81, 0xc3, 0x91, 0x08, 0x07, 0xfb, 0x42, 0xe8, 0xa0, 0x17, 0x42, 0x0c, 0xdc, 0x3d,
-	0x47, 0x4f, 0xe8, 0xad, 0x0e, 0x46, 0x7d, 0xd3, 0x05, 0x42, 0x1d, 0x40, 0x08, 0xf1, 0x64, 0xe9,
-	0x2f, 0xda, 0xe2, 0xae, 0x87, 0x43, 0x6d, 0x79, 0xe5, 0xbe, 0x3c, 0xc9, 0xab, 0xe8, 0x03, 0x19,
-	0x17, 0xa6, 0x38, 0xe4, 0x74, 0x86, 0xc3, 0x5a, 0x4b, 0xf5, 0x55, 0xa4, 0xae, 0x6f, 0xcc, 0x99,
-	0x8d, 0xeb, 0x94, 0xce, 0x11, 0x2b, 0x55, 0xa6, 0x75, 0x62, 0x2c, 0xeb, 0x39, 0x36, 0x6e, 0x27,
-	0xeb, 0x94, 0xfe, 0xa3, 0xa7, 0x93, 0x52, 0xc9, 0xa0, 0xef, 0xfc, 0x9a, 0x10, 0xfd, 0x00, 0x0e,
-	0x5f, 0xa4, 0xd6, 0x22, 0x97, 0x74, 0x89, 0x9e, 0x36, 0x22, 0x97, 0xae, 0x77, 0xbe, 0x9c, 0x75,
-	0x1e, 0x1b, 0x3b, 0xe4, 0x0d, 0xa3, 0x2b, 0x64, 0xca, 0x89, 0xb4, 0xb6, 0xf3, 0xee, 0xad, 0x46,
-	0x8f, 0xb7, 0xd4, 0x2e, 0x94, 0xb9, 0x5f, 0x9d, 0x5c, 0x28, 0xb3, 0x3a, 0x85, 0x91, 0x4a, 0x07,
-	0x83, 0x10, 0xe2, 0x19, 0x6f, 0x42, 0xf4, 0x8c, 0x6c, 0x57, 0xa5, 0xc2, 0xc8, 0xbf, 0x1e, 0xd8,
-	0xcd, 0x35, 0x7a, 0xce, 0x9b, 0xc6, 0xe8, 0xad, 0x5e, 0xb7, 0xfc, 0xd3, 0xff, 0x47, 0x13, 0x1c,
-	0xbe, 0xf3, 0xb7, 0x87, 0xdd, 0xfd, 0xd6, 0x07, 0x1a, 0xe1, 0x60, 0xb7, 0x59, 0x71, 0xbf, 0xb7,
-	0x67, 0xee, 0x86, 0xef, 0x7e, 0x03, 0x00, 0x00, 0xff, 0xff, 0x63, 0x88, 0xa5, 0x8b, 0xf1, 0x01,
-	0x00, 0x00,
+	// 848 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4f, 0x6f, 0xe3, 0x44,
+	0x14, 0xdf, 0x89, 0x13, 0xa7, 0x79, 0x71, 0x52, 0x33, 0x02, 0xd6, 0x54, 0x80, 0x22, 0x57, 0x02,
+	0x6b, 0x0f, 0x0b, 0x64, 0xb9, 0x00, 0x17, 0x96, 0xad, 0xcb, 0x56, 0x2a, 0x6d, 0xf4, 0xb2, 0x39,
+	0xc0, 0x81, 0xca, 0xb5, 0xa7, 0x95, 0xd5, 0xc4, 0x76, 0x67, 0xc6, 0x95, 0x2a, 0xc1, 0x9d, 0x2b,
+	0x1f, 0x80, 0x6f, 0xc3, 0x07, 0xe2, 0x23, 0xa0, 0x99, 0xb1, 0x9d, 0xc4, 0xd9, 0xee, 0x22, 0xf6,
+	0x94, 0x79, 0x7f, 0xfc, 0x7b, 0xbf, 0x79, 0xef, 0xf7, 0x46, 0x01, 0x27, 0xce, 0xaf, 0x52, 0xce,
+	0x9e, 0x16, 0x3c, 0x97, 0x39, 0xb5, 0x8d, 0xe5, 0xff, 0x06, 0x7b, 0xc7, 0x2c, 0x92, 0x25, 0x67,
+	0x82, 0x3a, 0x40, 0xee, 0x3c, 0x32, 0xb1, 0x02, 0x82, 0xe4, 0x8e, 0x52, 0xe8, 0x5e, 0xa6, 0x91,
+	0xf0, 0x3a, 0x13, 0x12, 0x10, 0xd4, 0x67, 0xea, 0x41, 0xbf, 0x2c, 0x92, 0x48, 0x32, 0xe1, 0x59,
+	0x13, 0x12, 0x74, 0xb1, 0x36, 0xa9, 0x0b, 0xd6, 0xdd, 0xb3, 0xa9, 0xd7, 0x9d, 0x58, 0x41, 0x07,
+	0xd5, 0x51, 0xa1, 0xdd, 0x7a, 0xbd, 0x09, 0x09, 0x1c, 0x24, 0xb7, 0xf4, 0x7d, 0xe8, 0x89, 0x38,
+	0x5a, 0x32, 0xcf, 0xd6, 0x70, 0xc6, 0xf0, 0xff, 0x22, 0xd0, 0x0b, 0x33, 0xc9, 0xef, 0xe9, 0xa7,
+	0x40, 0x4a, 0x8f, 0x4c, 0x48, 0x30, 0x9c, 0xba, 0x4f, 0x2b, 0xa6, 0x35, 0x31, 0x24, 0xa5, 0x8a,
+	0x17, 0x5e, 0xe7, 0xa1, 0x78, 0x41, 0x0f, 0xa1, 0xc7, 0x23, 0xc9, 0x12, 0xcf, 0x9a, 0x58, 0xc1,
+	0x70, 0x3a, 0xaa, 0x73, 0x50, 0x39, 0xd1, 0xc4, 0xe8, 0x17, 0xe0, 0x14, 0x3c, 0x4f, 0xca, 0x58,
+	0x5e, 0x28, 0x87, 0xd7, 0xd5, 0x78, 0xce, 0x66, 0x2e, 0x0e, 0xab, 0x0c, 0x65, 0xf8, 0x5f, 0x42,
+	0x57, 0xfd, 0x2a, 0xf6, 0x77, 0xd1, 0xb2, 0x64, 0x9a, 0x21, 0x41, 0x63, 0xa8, 0x0e, 0xc9, 0x74,
+	0xc5, 0x34, 0x2d, 0x0b, 0xf5, 0xd9, 0x3f, 0x82, 0x9e, 0x2e, 0x49, 0x3f, 0x01, 0xa8, 0x6b, 0xa5,
+	0x89, 0xfe, 0x6e, 0x80, 0x83, 0xca, 0x73, 0x92, 0xd0, 0x8f, 0x61, 0xa0, 0xf2, 0x85, 0x8c, 0x56,
+	0x45, 0x05, 0xb0, 0x76, 0xf8, 0x12, 0x6c, 0x8c, 0x64, 0x9a, 0x5d, 0xd3, 0xc7, 0xd0, 0x2f, 0x05,
+	0xe3, 0x6b, 0x0c, 0x5b, 0x99, 0x27, 0x6d, 0xfc, 0x4e, 0x1b, 0x5f, 0xf7, 0x3b, 0xe7, 0xcc, 0xb3,
+	0xea, 0x7e, 0xe7, 0x9c, 0x6d, 0x57, 0xed, 0xb6, 0xab, 0xfe, 0x4d, 0xa0, 0xff, 0x13, 0x13, 0x22,
+	0xba, 0x66, 0xaa, 0x9f, 0x42, 0x46, 0xd7, 0xe6, 0xc6, 0xe3, 0x75, 0x3f, 0xe7, 0xca, 0x89, 0x26,
+	0x46, 0x3f, 0x03, 0x9b, 0x6b, 0x9a, 0xd5, 0x64, 0xc6, 0x1b, 0x9d, 0x4c, 0xb3, 0x6b, 0xac, 0xa2,
+	0x6a, 0x78, 0x57, 0x9e, 0xf5, 0xd0, 0xf0, 0xae, 0x14, 0xd9, 0x54, 0x32, 0x2e, 0x34, 0xa5, 0x11,
+	0x1a, 0x63, 0x9b, 0x6c, 0xaf, 0x45, 0x56, 0x49, 0x91, 0x65, 0x92, 0x71, 0x96, 0x68, 0x49, 0x59,
+	0x58, 0x9b, 0xfe, 0x4b, 0xb0, 0x17, 0x5a, 0x95, 0xef, 0x2a, 0x2a, 0x9f, 0xc3, 0x78, 0xc6, 0x59,
+	0x92, 0xc6, 0x12, 0xd9, 0x6d, 0xc9, 0x84, 0xfc, 0xdf, 0xe3, 0x08, 0xa0, 0x6f, 0x7a, 0x21, 0x2a,
+	0x81, 0xb6, 0x5b, 0x55, 0x87, 0xfd, 0xcf, 0x61, 0xbf, 0xa9, 0x29, 0x8a, 0x3c, 0x13, 0x6c, 0x3d,
+	0x4b, 0xb2, 0x31, 0x4b, 0xff, 0x0f, 0x02, 0x2e, 0xb2, 0x38, 0x5f, 0xad, 0x58, 0x96, 0xbc, 0x95,
+	0x9f, 0x03, 0xe4, 0x46, 0xd3, 0x1a, 0x21, 0xb9, 0xf9, 0xef, 0x74, 0xe8, 0x21, 0x8c, 0xd2, 0x2c,
+	0x5e, 0x96, 0x09, 0xbb, 0x30, 0xfb, 0xa5, 0x46, 0xb4, 0x87, 0x4e, 0xe5, 0xd4, 0x5a, 0xf7, 0x7f,
+	0x87, 0xf7, 0x36, 0x98, 0x54, 0xac, 0xbf, 0x87, 0x7d, 0x5e, 0x3b, 0x23, 0x99, 0xe6, 0x99, 0xd0,
+	0x6f, 0xcb, 0x70, 0xfa, 0x61, 0x53, 0x6b, 0x2b, 0x8c, 0xed, 0x74, 0x25, 0x2f, 0x91, 0x97, 0x3c,
+	0x36, 0x1b, 0x36, 0x5e, 0x93, 0x9c, 0x6b, 0x2f, 0x56, 0x51, 0x3f, 0x84, 0xf1, 0x36, 0xd4, 0xdb,
+	0x96, 0xaf, 0x69, 0x68, 0x67, 0xb3, 0xa1, 0x7f, 0x12, 0xd8, 0xc7, 0x16, 0x85, 0x77, 0xbf, 0xc4,
+	0x1b, 0x17, 0xdd, 0x28, 0xf4, 0xc1, 0xcd, 0x28, 0xfd, 0x43, 0xd8, 0xff, 0x91, 0x49, 0xfd, 0x44,
+	0xd6, 0x23, 0x76, 0xc1, 0xba, 0x61, 0xf7, 0xd5, 0xa5, 0xd4, 0xd1, 0xff, 0x06, 0x46, 0xc7, 0xf9,
+	0x32, 0x39, 0xc9, 0xea, 0x94, 0x8d, 0xf1, 0x92, 0x37, 0xab, 0xed, 0x57, 0x80, 0x59, 0x5e, 0x94,
+	0xcb, 0x88, 0xa7, 0xf2, 0x5e, 0xf5, 0x25, 0xce, 0xcb, 0x4c, 0x6a, 0xf0, 0x2e, 0x1a, 0x43, 0x15,
+	0x14, 0xe5, 0xaa, 0xea, 0x95, 0x3a, 0x2a, 0xfc, 0xcb, 0x32, 0xbe, 0x61, 0x72, 0x47, 0x3e, 0x3f,
+	0x68, 0x37, 0xd6, 0x61, 0xff, 0x18, 0x6c, 0xe3, 0xd2, 0x3d, 0x97, 0x11, 0x37, 0xd8, 0x16, 0x1a,
+	0x63, 0x5d, 0xb1, 0xf3, 0x9a, 0x8a, 0x56, 0x53, 0xf1, 0xc9, 0xd7, 0xd0, 0xd3, 0x2f, 0x0f, 0x1d,
+	0x40, 0x2f, 0x3c, 0x7b, 0x85, 0x3f, 0xbb, 0x8f, 0xe8, 0x10, 0xfa, 0x33, 0x3c, 0x3f, 0x5a, 0xbc,
+	0x78, 0xe5, 0x12, 0xba, 0x07, 0xdd, 0xc5, 0x3c, 0x44, 0xb7, 0xa3, 0x32, 0x8e, 0x4f, 0x17, 0xf3,
+	0x97, 0xae, 0xf5, 0xe4, 0x3b, 0xb0, 0x8d, 0x54, 0xa8, 0x0b, 0xce, 0x2c, 0xc4, 0xf9, 0xf9, 0xd9,
+	0xf3, 0xd3, 0x93, 0x5f, 0xc2, 0x23, 0xf7, 0x11, 0xa5, 0x30, 0x9e, 0x9d, 0xcf, 0x16, 0xa7, 0xcf,
+	0xf1, 0x02, 0xc3, 0x17, 0xe1, 0x99, 0x02, 0x51, 0x88, 0xc6, 0xe7, 0x76, 0xa6, 0xff, 0x10, 0x18,
+	0x54, 0x9b, 0x98, 0x73, 0xfa, 0x2d, 0xf4, 0x2b, 0x83, 0x36, 0xa3, 0xdf, 0x7e, 0x1b, 0x0e, 0x1e,
+	0xef, 0xf8, 0x9b, 0x4d, 0x18, 0x34, 0x2a, 0xa1, 0xde, 0x8e, 0x70, 0xea, 0xef, 0x3f, 0x7a, 0x4d,
+	0xa4, 0x42, 0x98, 0xc2, 0x5e, 0x2d, 0x03, 0xda, 0x94, 0x69, 0x09, 0xe3, 0xa0, 0x79, 0xa3, 0x4d,
+	0xde, 0x57, 0x60, 0x1b, 0x55, 0xd0, 0x0f, 0x1a, 0x65, 0x6d, 0xaa, 0xe4, 0x60, 0x47, 0x70, 0x97,
+	0xb6, 0xfe, 0x6f, 0xf0, 0xec, 0xdf, 0x01, 0x00, 0xb0, 0x3c, 0xb9, 0x2d, 0x2b, 0x08, 0x00, 0x00,
 }
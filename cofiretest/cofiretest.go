@@ -0,0 +1,127 @@
+// Package cofiretest runs cofire processors in goka's in-memory tester, so
+// that the learning protocol can be tested end-to-end without Kafka.
+package cofiretest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/goka"
+	"github.com/lovoo/goka/tester"
+)
+
+// Group is the cofire group of the learner run by a Cluster.
+const Group goka.Group = "cofire"
+
+// Cluster runs a learner and a refeeder processor in goka's tester. Feeding
+// ratings and updates blocks until all resulting loopbacks and refeeds are
+// processed. The refeeder refeeds without delay.
+type Cluster struct {
+	t       tester.T
+	tester  *tester.Tester
+	refeeds *tester.QueueTracker
+	keys    map[string]bool
+	m       sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New starts a Cluster with the learner of NewLearner and the refeeder of
+// NewRefeeder.
+func New(t tester.T, validator cofire.Validator, params cofire.Parameters, opts ...cofire.LearnerOption) *Cluster {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cluster{
+		t:      t,
+		tester: tester.New(t),
+		keys:   make(map[string]bool),
+		cancel: cancel,
+	}
+	c.refeeds = c.tester.NewQueueTracker(fmt.Sprintf("%s-refeed", Group))
+
+	for _, gg := range []*goka.GroupGraph{
+		cofire.NewLearner(Group, validator, params, opts...),
+		cofire.NewRefeeder(Group, 0),
+	} {
+		p, err := goka.NewProcessor(nil, gg, goka.WithTester(c.tester))
+		if err != nil {
+			t.Fatalf("error creating processor %s: %v", gg.Group(), err)
+		}
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			if err := p.Run(ctx); err != nil {
+				t.Errorf("error running processor: %v", err)
+			}
+		}()
+	}
+	return c
+}
+
+// Tester returns the tester of the cluster.
+func (c *Cluster) Tester() *tester.Tester {
+	return c.tester
+}
+
+// Rate feeds ratings into the learner's input topic, keyed by user.
+func (c *Cluster) Rate(ratings ...cofire.Rating) {
+	for i := range ratings {
+		r := &ratings[i]
+		c.addKeys(r.UserId, r.ProductId)
+		c.tester.Consume(fmt.Sprintf("%s-input", Group), r.UserId, r)
+	}
+}
+
+// Update feeds an update of the features of key into the learner's update
+// topic.
+func (c *Cluster) Update(key string, u *cofire.Update) {
+	c.addKeys(key)
+	c.tester.Consume(fmt.Sprintf("%s-update", Group), key, u)
+}
+
+func (c *Cluster) addKeys(keys ...string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for _, key := range keys {
+		c.keys[key] = true
+	}
+}
+
+// Entry returns the entry of key in the learner's table or nil if missing.
+func (c *Cluster) Entry(key string) *cofire.Entry {
+	e, _ := c.tester.TableValue(goka.GroupTable(Group), key).(*cofire.Entry)
+	return e
+}
+
+// Model returns a copy of the entries of all users and products fed so far.
+func (c *Cluster) Model() cofire.MemoryModel {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m := make(cofire.MemoryModel)
+	for key := range c.keys {
+		if e := c.Entry(key); e != nil {
+			m[key] = e
+		}
+	}
+	return m
+}
+
+// Refeeds returns the messages sent to the refeeder since the last call.
+func (c *Cluster) Refeeds() []*cofire.Message {
+	var msgs []*cofire.Message
+	for {
+		_, v, ok := c.refeeds.Next()
+		if !ok {
+			return msgs
+		}
+		msgs = append(msgs, v.(*cofire.Message))
+	}
+}
+
+// Close stops the processors of the cluster.
+func (c *Cluster) Close() {
+	c.cancel()
+	c.wg.Wait()
+}
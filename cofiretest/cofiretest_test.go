@@ -0,0 +1,74 @@
+package cofiretest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lovoo/cofire"
+)
+
+// iterValidator counts the validations per remaining iterations.
+type iterValidator map[uint32]int
+
+func (v iterValidator) ValidateContext(val *cofire.Validation) {
+	v[val.Iters]++
+}
+
+func TestCluster(t *testing.T) {
+	var (
+		params = cofire.Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 3}
+		v      = make(iterValidator)
+		c      = New(t, nil, params, cofire.WithContextValidator(v), cofire.WithRatedItems(10, 0))
+	)
+	defer c.Close()
+
+	var ratings []cofire.Rating
+	for u := 0; u < 4; u++ {
+		for p := 0; p <= u; p++ {
+			ratings = append(ratings, cofire.Rating{
+				UserId:    fmt.Sprintf("user%d", u),
+				ProductId: fmt.Sprintf("prod%d", p),
+				Score:     float64(u + p),
+			})
+		}
+	}
+	c.Rate(ratings...)
+
+	// every rating is validated and refed in each iteration but the last
+	for iters := uint32(1); iters <= 3; iters++ {
+		if v[iters] != len(ratings) {
+			t.Errorf("expected %d validations with %d iterations left, got %d", len(ratings), iters, v[iters])
+		}
+	}
+	if refeeds := c.Refeeds(); len(refeeds) != 2*len(ratings) {
+		t.Errorf("expected %d refeeds, got %d", 2*len(ratings), len(refeeds))
+	}
+
+	// users learn U and products learn P once per rating and iteration
+	m := c.Model()
+	for u := 0; u < 4; u++ {
+		e := m[fmt.Sprintf("user%d", u)]
+		if e.GetU().GetUpdates() != uint64(3*(u+1)) {
+			t.Errorf("user%d: expected %d U updates, got %d", u, 3*(u+1), e.GetU().GetUpdates())
+		}
+		if !e.HasRated("prod0") {
+			t.Errorf("user%d: prod0 not rated", u)
+		}
+	}
+	for p := 0; p < 4; p++ {
+		e := c.Entry(fmt.Sprintf("prod%d", p))
+		if e.GetP().GetUpdates() != uint64(3*(4-p)) {
+			t.Errorf("prod%d: expected %d P updates, got %d", p, 3*(4-p), e.GetP().GetUpdates())
+		}
+	}
+
+	// updates replace the features
+	f := &cofire.Features{V: []float64{1, 2, 3}, Bias: 1}
+	c.Update("prod0", &cofire.Update{P: f})
+	if p := c.Entry("prod0").GetP(); p.Bias != 1 || p.V[2] != 3 {
+		t.Errorf("features not updated: %v", p)
+	}
+	if c.Entry("unknown") != nil {
+		t.Errorf("unexpected entry of unknown key")
+	}
+}
@@ -0,0 +1,178 @@
+package cofire
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// CrossValidator runs k-fold cross-validation of the learner's SGD on a set of
+// ratings. The ratings are randomly assigned to Folds folds; each fold is
+// predicted by a model trained on the remaining folds.
+type CrossValidator struct {
+	// Folds is the number of folds, at least 2.
+	Folds int
+	// Params are the parameters used to train each fold.
+	Params Parameters
+	// Seed seeds the assignment of ratings to folds and the random
+	// initialization of the features.
+	Seed int64
+	// Ranking evaluates the ranking metrics of each fold if not nil. Its Bias
+	// and Train fields are set per fold.
+	Ranking *RankingEvaluator
+}
+
+// FoldMetrics are the metrics of a single fold.
+type FoldMetrics struct {
+	// RMSE is the root mean square error of the test ratings.
+	RMSE float64
+	// MAE is the mean absolute error of the test ratings.
+	MAE float64
+	// Count is the number of test ratings predicted. Ratings of users or
+	// products not in the training folds cannot be predicted.
+	Count int
+	// Ranking are the ranking metrics if evaluated.
+	Ranking *RankingMetrics
+}
+
+// Stat is the mean and standard deviation of a metric over the folds.
+type Stat struct {
+	Mean   float64
+	StdDev float64
+}
+
+func (s Stat) String() string {
+	return fmt.Sprintf("%.6f ± %.6f", s.Mean, s.StdDev)
+}
+
+// CrossValidation is the result of a cross-validation.
+type CrossValidation struct {
+	Folds []FoldMetrics
+
+	RMSE Stat
+	MAE  Stat
+
+	// ranking metrics, only set if evaluated
+	Precision Stat
+	Recall    Stat
+	NDCG      Stat
+	MAP       Stat
+	AUC       Stat
+}
+
+func (cv *CrossValidation) String() string {
+	var b strings.Builder
+	for i, f := range cv.Folds {
+		fmt.Fprintf(&b, "Fold %d: RMSE: %.6f MAE: %.6f Count: %d", i, f.RMSE, f.MAE, f.Count)
+		if f.Ranking != nil {
+			fmt.Fprintf(&b, " %v", f.Ranking)
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "RMSE: %v MAE: %v", cv.RMSE, cv.MAE)
+	if len(cv.Folds) > 0 && cv.Folds[0].Ranking != nil {
+		k := cv.Folds[0].Ranking.K
+		fmt.Fprintf(&b, "\nPrecision@%d: %v Recall@%d: %v NDCG@%d: %v MAP: %v AUC: %v",
+			k, cv.Precision, k, cv.Recall, k, cv.NDCG, cv.MAP, cv.AUC)
+	}
+	return b.String()
+}
+
+// Run cross-validates the ratings.
+func (cv *CrossValidator) Run(ratings []Rating) (*CrossValidation, error) {
+	if cv.Folds < 2 {
+		return nil, fmt.Errorf("invalid number of folds: %d", cv.Folds)
+	}
+	if len(ratings) < cv.Folds {
+		return nil, errors.New("fewer ratings than folds")
+	}
+
+	var (
+		rnd   = rand.New(rand.NewSource(cv.Seed))
+		fold  = make([]int, len(ratings))
+		train = make([]Rating, 0, len(ratings))
+		test  = make([]Rating, 0, len(ratings)/cv.Folds+1)
+		res   = &CrossValidation{Folds: make([]FoldMetrics, cv.Folds)}
+	)
+	for i, j := range rnd.Perm(len(ratings)) {
+		fold[j] = i % cv.Folds
+	}
+
+	for k := range res.Folds {
+		train, test = train[:0], test[:0]
+		for i, r := range ratings {
+			if fold[i] == k {
+				test = append(test, r)
+			} else {
+				train = append(train, r)
+			}
+		}
+		m, err := cv.fold(train, test, rnd)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %v", k, err)
+		}
+		res.Folds[k] = *m
+	}
+
+	res.RMSE = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.RMSE })
+	res.MAE = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.MAE })
+	if cv.Ranking != nil {
+		res.Precision = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.Ranking.Precision })
+		res.Recall = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.Ranking.Recall })
+		res.NDCG = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.Ranking.NDCG })
+		res.MAP = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.Ranking.MAP })
+		res.AUC = foldStat(res.Folds, func(f *FoldMetrics) float64 { return f.Ranking.AUC })
+	}
+	return res, nil
+}
+
+// fold trains a model and evaluates it with the test ratings.
+func (cv *CrossValidator) fold(train, test []Rating, rnd *rand.Rand) (*FoldMetrics, error) {
+	var (
+		t = NewLocalTrainer(nil, cv.Params, WithSeed(rnd.Int63()))
+		v = NewErrorValidator()
+	)
+	t.Train(train)
+	model := t.Model()
+
+	for _, r := range test {
+		u, p := model[r.UserId].GetU(), model[r.ProductId].GetP()
+		if u == nil || p == nil {
+			continue
+		}
+		v.Validate(u.Predict(p, t.Bias()), r.Score)
+	}
+	m := &FoldMetrics{RMSE: v.RMSE(), MAE: v.MAE(), Count: v.Count()}
+
+	if cv.Ranking != nil {
+		ev := *cv.Ranking
+		ev.Bias = t.Bias()
+		ev.Train = train
+		ranking, err := ev.Evaluate(model, test)
+		if err != nil {
+			return nil, err
+		}
+		m.Ranking = ranking
+	}
+	return m, nil
+}
+
+// foldStat computes the mean and standard deviation of a metric of the folds.
+func foldStat(folds []FoldMetrics, metric func(*FoldMetrics) float64) Stat {
+	var sum, sq float64
+	for i := range folds {
+		sum += metric(&folds[i])
+	}
+	n := float64(len(folds))
+	mean := sum / n
+	for i := range folds {
+		d := metric(&folds[i]) - mean
+		sq += d * d
+	}
+	if n < 2 {
+		return Stat{Mean: mean}
+	}
+	return Stat{Mean: mean, StdDev: math.Sqrt(sq / (n - 1))}
+}
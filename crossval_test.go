@@ -0,0 +1,81 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func crossValRatings() []Rating {
+	var ratings []Rating
+	for u := 0; u < 20; u++ {
+		for p := 0; p < 20; p++ {
+			score := 1.0
+			if (u+p)%2 == 0 {
+				score = 5
+			}
+			ratings = append(ratings, Rating{
+				UserId:    fmt.Sprintf("user%d", u),
+				ProductId: fmt.Sprintf("prod%d", p),
+				Score:     score,
+			})
+		}
+	}
+	return ratings
+}
+
+func TestCrossValidator(t *testing.T) {
+	params := Parameters{Rank: 4, Gamma: 0.01, Lambda: 0.01, Iterations: 5}
+	cv := &CrossValidator{
+		Folds:   4,
+		Params:  params,
+		Seed:    1,
+		Ranking: &RankingEvaluator{K: 5, Threshold: 4},
+	}
+	res, err := cv.Run(crossValRatings())
+	if err != nil {
+		t.Fatalf("error cross-validating: %v", err)
+	}
+	if len(res.Folds) != 4 {
+		t.Fatalf("unexpected number of folds: %d", len(res.Folds))
+	}
+	var count int
+	for i, f := range res.Folds {
+		if f.Ranking == nil || f.Ranking.Users == 0 {
+			t.Errorf("fold %d has no ranking metrics", i)
+		}
+		count += f.Count
+	}
+	if count != 400 {
+		t.Errorf("unexpected number of predicted ratings: %d", count)
+	}
+	if res.RMSE.Mean <= 0 || math.IsNaN(res.RMSE.StdDev) || res.MAE.Mean > res.RMSE.Mean {
+		t.Errorf("unexpected errors: RMSE %v MAE %v", res.RMSE, res.MAE)
+	}
+
+	// same seed, same result
+	again, err := cv.Run(crossValRatings())
+	if err != nil {
+		t.Fatalf("error cross-validating: %v", err)
+	}
+	if again.RMSE != res.RMSE {
+		t.Errorf("cross-validation not reproducible: %v != %v", again.RMSE, res.RMSE)
+	}
+}
+
+func TestCrossValidator_Invalid(t *testing.T) {
+	if _, err := (&CrossValidator{Folds: 1}).Run(crossValRatings()); err == nil {
+		t.Errorf("expected error with a single fold")
+	}
+	if _, err := (&CrossValidator{Folds: 3}).Run(crossValRatings()[:2]); err == nil {
+		t.Errorf("expected error with fewer ratings than folds")
+	}
+}
+
+func TestFoldStat(t *testing.T) {
+	folds := []FoldMetrics{{RMSE: 1}, {RMSE: 2}, {RMSE: 3}}
+	s := foldStat(folds, func(f *FoldMetrics) float64 { return f.RMSE })
+	if !near(s.Mean, 2) || !near(s.StdDev, 1) {
+		t.Errorf("unexpected stat: %v", s)
+	}
+}
@@ -0,0 +1,78 @@
+package cofire
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DecayValidator validates each prediction calculating an exponentially
+// decayed root mean square error and mean absolute error. The weight of a
+// prediction halves every half-life, so that the errors reflect the recent
+// quality of the model.
+type DecayValidator struct {
+	halfLife time.Duration
+	sum      float64
+	abs      float64
+	weight   float64
+	last     time.Time
+	now      func() time.Time
+	m        sync.RWMutex
+}
+
+// NewDecayValidator creates a DecayValidator with the given half-life.
+func NewDecayValidator(halfLife time.Duration) *DecayValidator {
+	return &DecayValidator{
+		halfLife: halfLife,
+		now:      time.Now,
+	}
+}
+
+// Validate validates the prediction given a score.
+func (v *DecayValidator) Validate(prediction, score float64) {
+	e := score - prediction
+	v.m.Lock()
+	now := v.now()
+	f := v.decay(now)
+	v.sum = v.sum*f + e*e
+	v.abs = v.abs*f + math.Abs(e)
+	v.weight = v.weight*f + 1
+	v.last = now
+	v.m.Unlock()
+}
+
+// decay returns the factor by which the accumulated errors decayed since the
+// last validation.
+func (v *DecayValidator) decay(now time.Time) float64 {
+	if v.last.IsZero() || !now.After(v.last) {
+		return 1
+	}
+	return math.Exp2(-float64(now.Sub(v.last)) / float64(v.halfLife))
+}
+
+// RMSE returns the current decayed root mean square error.
+func (v *DecayValidator) RMSE() float64 {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	if v.weight == 0 {
+		return 0.0
+	}
+	return math.Sqrt(v.sum / v.weight)
+}
+
+// MAE returns the current decayed mean absolute error.
+func (v *DecayValidator) MAE() float64 {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	if v.weight == 0 {
+		return 0.0
+	}
+	return v.abs / v.weight
+}
+
+// Weight returns the decayed number of values validated until now.
+func (v *DecayValidator) Weight() float64 {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	return v.weight * v.decay(v.now())
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/lovoo/cofire"
@@ -11,25 +13,16 @@ import (
 )
 
 // StartLearner starts a Cofire processor that factorizes a rating matrix with
-// SGD. A simple validator prints to stdout the RMSE every second.
-func StartLearner(ctx context.Context, brokers []string, group goka.Group, params cofire.Parameters) func() error {
+// SGD. The learner records its metrics in m, including the RMSE of the
+// training ratings as validator "train".
+func StartLearner(ctx context.Context, brokers []string, group goka.Group, params cofire.Parameters, m *cofire.Metrics) func() error {
 	return func() error {
-		// validator prints the RMSE every couple of seconds
 		validator := cofire.NewErrorValidator()
-		go func() {
-			for {
-				cnt := validator.Count()
-				if cnt > 0 {
-					rmse := validator.RMSE()
-					fmt.Printf("RMSE: %.8f Count: %d \n", rmse, cnt)
-				}
-				time.Sleep(1 * time.Second)
-			}
-		}()
+		m.AddValidator("train", validator)
 
 		// create a new group graph with the cofire group, validator and SGD
 		// parameters.
-		gg := cofire.NewLearner(group, validator, params)
+		gg := cofire.NewLearner(group, validator, params, cofire.WithMetrics(m))
 		p, err := goka.NewProcessor(brokers, gg)
 		if err != nil {
 			return err
@@ -40,6 +33,23 @@ func StartLearner(ctx context.Context, brokers []string, group goka.Group, param
 	}
 }
 
+// StartMetrics serves the metrics on addr at /metrics until ctx is done.
+func StartMetrics(ctx context.Context, addr string, m *cofire.Metrics) func() error {
+	return func() error {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		srv := &http.Server{Addr: addr, Handler: mux}
+		go func() {
+			<-ctx.Done()
+			srv.Close()
+		}()
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}
+}
+
 // StartProducer starts a producer that emits a slice of ratings into the input
 // of the learner, one rating every 5 milliseconds.
 func StartProducer(ctx context.Context, brokers []string, group goka.Group, ratings []cofire.Rating) func() error {
@@ -71,9 +81,9 @@ func StartProducer(ctx context.Context, brokers []string, group goka.Group, rati
 // StartRefeeder starts a Cofire refeeder processor, ie, a process that refeeds
 // the stream into the learner's input after a delay. This can be used to
 // train for multiple iterations.
-func StartRefeeder(ctx context.Context, brokers []string, group goka.Group, delay time.Duration) func() error {
+func StartRefeeder(ctx context.Context, brokers []string, group goka.Group, delay time.Duration, m *cofire.Metrics) func() error {
 	return func() error {
-		gg := cofire.NewRefeeder(group, delay)
+		gg := cofire.NewRefeeder(group, delay, cofire.WithRefeederMetrics(m))
 		p, err := goka.NewProcessor(brokers, gg)
 		if err != nil {
 			return err
@@ -97,10 +107,12 @@ func CreateView(brokers []string, group goka.Group) (*goka.View, func(ctx contex
 }
 
 // StartValidator starts a go routine that loops over all given ratings and
-// calculates the RSME calculating the ratings with the error predicted from
-// the model.
-func StartValidator(ctx context.Context, view *goka.View, ratings []cofire.Rating, params cofire.Parameters) func() error {
+// calculates the RMSE of the scores predicted by the model. The errors of the
+// last pass are reported in m as validator "test".
+func StartValidator(ctx context.Context, view *goka.View, ratings []cofire.Rating, params cofire.Parameters, m *cofire.Metrics) func() error {
 	return func() error {
+		last := new(lastPass)
+		m.AddValidator("test", last)
 		for {
 			select {
 			case <-ctx.Done():
@@ -141,9 +153,45 @@ func StartValidator(ctx context.Context, view *goka.View, ratings []cofire.Ratin
 				sgd.Add(r.Score)
 				v.Validate(u.Predict(p, sgd.Bias()), r.Score)
 			}
+			last.v.Store(v)
 			time.Sleep(3 * time.Second)
-			fmt.Printf("TEST RSME: %.8f Count: %d\n", v.RMSE(), v.Count())
 		}
-		return nil
 	}
 }
+
+// lastPass reports the errors of the last pass of StartValidator.
+type lastPass struct {
+	v atomic.Value // *cofire.ErrorValidator
+}
+
+func (l *lastPass) RMSE() float64 {
+	if v, ok := l.v.Load().(*cofire.ErrorValidator); ok {
+		return v.RMSE()
+	}
+	return 0
+}
+
+func (l *lastPass) MAE() float64 {
+	if v, ok := l.v.Load().(*cofire.ErrorValidator); ok {
+		return v.MAE()
+	}
+	return 0
+}
+
+// NewSplitter returns the splitter of a method putting 100-sample percent of
+// the ratings into the test set. Methods are random, temporal, stratified
+// (random per user) and last (the newest rating of each user).
+func NewSplitter(method string, sample int) (cofire.Splitter, error) {
+	fraction := float64(100-sample) / 100
+	switch method {
+	case "random":
+		return &cofire.RandomSplitter{TestFraction: fraction, Seed: time.Now().UnixNano()}, nil
+	case "temporal":
+		return &cofire.TemporalSplitter{TestFraction: fraction}, nil
+	case "stratified":
+		return &cofire.StratifiedSplitter{TestFraction: fraction, Seed: time.Now().UnixNano()}, nil
+	case "last":
+		return &cofire.LeaveLastOutSplitter{N: 1}, nil
+	}
+	return nil, fmt.Errorf("unknown split method %q", method)
+}
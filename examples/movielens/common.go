@@ -1,43 +1,41 @@
 package movielens
 
 import (
-	"io/ioutil"
-	"log"
 	"math/rand"
-	"strconv"
-	"strings"
+	"os"
 
 	"github.com/lovoo/cofire"
+	"github.com/lovoo/cofire/ratings"
 )
 
-// ReadRatings reads a Movie Lens file and returns a slice of ratings
-func ReadRatings(fname string) []cofire.Rating {
-	dat, err := ioutil.ReadFile(fname)
+// ReadRatings reads a Movie Lens ratings.csv file, with or without header, or
+// a ratings.dat file, and returns a slice of ratings in random order.
+func ReadRatings(fname string) ([]cofire.Rating, error) {
+	format, err := ratings.FormatOf(fname)
 	if err != nil {
-		log.Fatal(err)
+		format = ratings.CSV
 	}
-
-	var ratings []cofire.Rating
-	for _, l := range strings.Split(string(dat), "\n") {
-		if l == "" {
-			continue
-		}
-		e := strings.Split(l, ",")
-		if len(e) != 4 {
-			log.Print(l)
-			log.Fatal("!= 4")
-		}
-
-		s, _ := strconv.ParseFloat(e[2], 64)
-		ratings = append(ratings, cofire.Rating{
-			UserId:    e[0],
-			ProductId: e[1],
-			Score:     s,
-		})
+	var rs []cofire.Rating
+	if format == ratings.CSV {
+		rs, err = readCSV(fname)
+	} else {
+		rs, err = ratings.ReadFile(fname, format)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	rand.Shuffle(len(ratings), func(i, j int) {
-		ratings[i], ratings[j] = ratings[j], ratings[i]
+	rand.Shuffle(len(rs), func(i, j int) {
+		rs[i], rs[j] = rs[j], rs[i]
 	})
-	return ratings
+	return rs, nil
+}
+
+func readCSV(fname string) ([]cofire.Rating, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ratings.ReadAll(ratings.NewCSVReader(f, ratings.WithColumns(0, 1, 2, 3)))
 }
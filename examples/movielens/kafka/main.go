@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/lovoo/cofire"
@@ -14,15 +15,17 @@ import (
 )
 
 var (
-	input      = flag.String("input", "/tmp/ratings", "input ratings file (Movie Lens format)")
-	group      = flag.String("group", "cofire-mlens", "consumer group for learner")
-	broker     = flag.String("broker", "localhost:9092", "a bootstrap Kafka broker")
-	sample     = flag.Int("sample", 80, "percentage of the input ratings used for training")
-	gamma      = flag.Float64("gamma", 0.001, "SGD gamma parameter")
-	lambda     = flag.Float64("lambda", 0.01, "SGD lambda parameter")
-	rank       = flag.Int("rank", 10, "number of latent features")
-	iterations = flag.Int("iterations", 1, "number of iterations")
-	delay      = flag.Duration("delay", time.Second, "reiteration delay")
+	input       = flag.String("input", "/tmp/ratings", "input ratings file (Movie Lens format)")
+	group       = flag.String("group", "cofire-mlens", "consumer group for learner")
+	broker      = flag.String("broker", "localhost:9092", "a bootstrap Kafka broker")
+	sample      = flag.Int("sample", 80, "percentage of the input ratings used for training")
+	split       = flag.String("split", "temporal", "split method: random, temporal, stratified or last")
+	gamma       = flag.Float64("gamma", 0.001, "SGD gamma parameter")
+	lambda      = flag.Float64("lambda", 0.01, "SGD lambda parameter")
+	rank        = flag.Int("rank", 10, "number of latent features")
+	iterations  = flag.Int("iterations", 1, "number of iterations")
+	delay       = flag.Duration("delay", time.Second, "reiteration delay")
+	metricsAddr = flag.String("metrics", ":9090", "address serving the Prometheus metrics")
 )
 
 func init() {
@@ -33,10 +36,7 @@ func main() {
 	var (
 		brokers = []string{*broker}
 		ggroup  = goka.Group(*group)
-		ratings = movielens.ReadRatings(*input)
 		ctx     = context.Background()
-		train   = ratings[:len(ratings)**sample/100]
-		test    = ratings[len(ratings)**sample/100:]
 		params  = cofire.Parameters{
 			Gamma:      *gamma,
 			Lambda:     *lambda,
@@ -45,17 +45,29 @@ func main() {
 		}
 	)
 
+	ratings, err := movielens.ReadRatings(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	splitter, err := examples.NewSplitter(*split, *sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	train, test := splitter.Split(ratings)
+
 	fmt.Printf("Train set: %d\n", len(train))
 	fmt.Printf("Test  set: %d\n", len(test))
 	fmt.Println(train[0:10])
 
 	grp, ctx := errgroup.WithContext(ctx)
-	grp.Go(examples.StartLearner(ctx, brokers, ggroup, params))
+	metrics := cofire.NewMetrics(ggroup)
+	grp.Go(examples.StartMetrics(ctx, *metricsAddr, metrics))
+	grp.Go(examples.StartLearner(ctx, brokers, ggroup, params, metrics))
 	grp.Go(examples.StartProducer(ctx, brokers, ggroup, train))
-	grp.Go(examples.StartRefeeder(ctx, brokers, ggroup, *delay))
+	grp.Go(examples.StartRefeeder(ctx, brokers, ggroup, *delay, metrics))
 	view, startView := examples.CreateView(brokers, ggroup)
 	grp.Go(startView(ctx))
-	grp.Go(examples.StartValidator(ctx, view, test, params))
+	grp.Go(examples.StartValidator(ctx, view, test, params, metrics))
 
 	if err := grp.Wait(); err != nil {
 		fmt.Println(err)
@@ -27,15 +27,16 @@ import (
 )
 
 var (
-	input      = flag.String("input", "/tmp/figure.jpg", "input figure to be used as rating")
-	group      = flag.String("group", "cofire-image", "consumer group for learner")
-	broker     = flag.String("broker", "localhost:9092", "a bootstrap Kafka broker")
-	sample     = flag.Int("sample", 80, "percentage of the input ratings used for training")
-	gamma      = flag.Float64("gamma", 0.001, "SGD gamma parameter")
-	lambda     = flag.Float64("lambda", 0.01, "SGD lambda parameter")
-	rank       = flag.Int("rank", 10, "number of latent features")
-	iterations = flag.Int("iterations", 1, "number of iterations")
-	delay      = flag.Duration("delay", time.Second, "reiteration delay")
+	input       = flag.String("input", "/tmp/figure.jpg", "input figure to be used as rating")
+	group       = flag.String("group", "cofire-image", "consumer group for learner")
+	broker      = flag.String("broker", "localhost:9092", "a bootstrap Kafka broker")
+	sample      = flag.Int("sample", 80, "percentage of the input ratings used for training")
+	gamma       = flag.Float64("gamma", 0.001, "SGD gamma parameter")
+	lambda      = flag.Float64("lambda", 0.01, "SGD lambda parameter")
+	rank        = flag.Int("rank", 10, "number of latent features")
+	iterations  = flag.Int("iterations", 1, "number of iterations")
+	delay       = flag.Duration("delay", time.Second, "reiteration delay")
+	metricsAddr = flag.String("metrics", ":9090", "address serving the Prometheus metrics")
 )
 
 func init() {
@@ -112,13 +113,13 @@ func startPicValidator(ctx context.Context, view *goka.View, ratings []cofire.Ra
 
 func main() {
 	var (
-		brokers = []string{*broker}
-		ggroup  = goka.Group(*group)
-		ratings = pixelreco.ReadRatings(*input)
-		ctx     = context.Background()
-		train   = ratings[:len(ratings)**sample/100]
-		test    = ratings[len(ratings)**sample/100:]
-		params  = cofire.Parameters{
+		brokers    = []string{*broker}
+		ggroup     = goka.Group(*group)
+		ratings, _ = pixelreco.ReadRatings(*input)
+		ctx        = context.Background()
+		train      = ratings[:len(ratings)**sample/100]
+		test       = ratings[len(ratings)**sample/100:]
+		params     = cofire.Parameters{
 			Gamma:      *gamma,
 			Lambda:     *lambda,
 			Rank:       *rank,
@@ -131,9 +132,11 @@ func main() {
 	fmt.Println(train[0:10])
 
 	grp, ctx := errgroup.WithContext(ctx)
-	grp.Go(examples.StartLearner(ctx, brokers, ggroup, params))
+	metrics := cofire.NewMetrics(ggroup)
+	grp.Go(examples.StartMetrics(ctx, *metricsAddr, metrics))
+	grp.Go(examples.StartLearner(ctx, brokers, ggroup, params, metrics))
 	grp.Go(examples.StartProducer(ctx, brokers, ggroup, train))
-	grp.Go(examples.StartRefeeder(ctx, brokers, ggroup, *delay))
+	grp.Go(examples.StartRefeeder(ctx, brokers, ggroup, *delay, metrics))
 	view, startView := examples.CreateView(brokers, ggroup)
 	grp.Go(startView(ctx))
 
@@ -154,7 +157,7 @@ func main() {
 	fmt.Println("View opened at http://localhost:9095/")
 	go http.ListenAndServe(":9095", root)
 
-	grp.Go(examples.StartValidator(ctx, view, test, params))
+	grp.Go(examples.StartValidator(ctx, view, test, params, metrics))
 	grp.Go(startPicValidator(ctx, view, test, params, func() *image.Gray {
 		f, err := os.Open(*input)
 		if err != nil {
@@ -29,79 +29,44 @@ func init() {
 	rand.Seed(int64(time.Now().Unix()))
 }
 
+// snapshotValidator validates every prediction and calls snapshot every n
+// validations.
+type snapshotValidator struct {
+	v        *cofire.ErrorValidator
+	n, k     int
+	snapshot func()
+}
+
+func (s *snapshotValidator) ValidateContext(v *cofire.Validation) {
+	s.v.Validate(v.Prediction, v.Rating.Score)
+	fmt.Printf("RSME: %.8f Count: %d\n", s.v.RMSE(), s.v.Count())
+	if s.k%s.n == 0 {
+		s.snapshot()
+	}
+	s.k++
+}
+
 func main() {
 	var (
 		ratings, img = pixelreco.ReadRatings(*input)
 		train        = ratings[:len(ratings)**sample/100]
-		test         = ratings[len(ratings)**sample/100:]
 		params       = cofire.Parameters{
 			Gamma:      *gamma,
 			Lambda:     *lambda,
 			Rank:       *rank,
 			Iterations: *iterations,
 		}
-		trainError = cofire.NewErrorValidator()
-		testError  = cofire.NewErrorValidator()
-		sgd        = cofire.NewSGD(params.Gamma, params.Lambda)
-		model      = make(map[string]*cofire.Entry)
-		animated   = &gif.GIF{}
-		k          = 0
-		n          = len(ratings) * *iterations / 20
+		testError = cofire.NewErrorValidator()
+		animated  = &gif.GIF{}
+		trainer   *cofire.LocalTrainer
 	)
 
-	for i := 0; i < *iterations; i++ {
-
-		// train model
-		for _, r := range train {
-			user := model[r.UserId]
-			if user == nil {
-				user = new(cofire.Entry)
-			}
-			if user.U == nil {
-				user.U = cofire.NewFeatures(*rank).Randomize()
-			}
-			product := model[r.ProductId]
-			if product == nil {
-				product = new(cofire.Entry)
-			}
-			if product.P == nil {
-				product.P = cofire.NewFeatures(*rank).Randomize()
-			}
-
-			// train
-			sgd.Apply(user.U, product.P, r.Score)
-			sgd.Apply(product.P, user.U, r.Score)
-
-			// update table
-			model[r.UserId] = user
-			model[r.ProductId] = product
-
-			trainError.Validate(user.U.Predict(product.P, sgd.Bias()), r.Score)
-			fmt.Printf("RSME: %.8f Count: %d\n", trainError.RMSE(), trainError.Count())
-
-			if k%n == 0 {
-				// check with test set
-				output := image.NewGray(img.Bounds())
-				for _, r := range ratings {
-					user := model[r.UserId]
-					product := model[r.ProductId]
-					if user == nil || user.U == nil || product == nil || product.P == nil {
-						continue
-					}
-
-					p := user.U.Predict(product.P, sgd.Bias())
-					x, _ := strconv.Atoi(r.UserId)
-					y, _ := strconv.Atoi(r.ProductId)
-					output.SetGray(x, y, color.Gray{Y: uint8(p)})
-				}
-				animated = pixelreco.AppendGif(animated, output)
-			}
-			k++
-		}
-
-		// check with test set
-		output := image.NewGray(img.Bounds())
-		_ = test
+	// draw predicts all pixels and returns the image.
+	draw := func(v *cofire.ErrorValidator) *image.Gray {
+		var (
+			output = image.NewGray(img.Bounds())
+			model  = trainer.Model()
+		)
 		for _, r := range ratings {
 			user := model[r.UserId]
 			product := model[r.ProductId]
@@ -109,15 +74,28 @@ func main() {
 				continue
 			}
 
-			p := user.U.Predict(product.P, sgd.Bias())
+			p := user.U.Predict(product.P, trainer.Bias())
 			x, _ := strconv.Atoi(r.UserId)
 			y, _ := strconv.Atoi(r.ProductId)
 			output.SetGray(x, y, color.Gray{Y: uint8(p)})
-
-			testError.Validate(p, r.Score)
+			if v != nil {
+				v.Validate(p, r.Score)
+			}
 		}
-		fmt.Printf("TEST RSME: %.8f Count: %d\n", testError.RMSE(), testError.Count())
-		animated = pixelreco.AppendGif(animated, output)
+		return output
 	}
+
+	trainer = cofire.NewLocalTrainer(nil, params, cofire.WithContextValidator(&snapshotValidator{
+		v: cofire.NewErrorValidator(),
+		n: len(ratings)**iterations/20 + 1,
+		snapshot: func() {
+			animated = pixelreco.AppendGif(animated, draw(nil))
+		},
+	}))
+	trainer.Train(train)
+
+	// check with all ratings
+	animated = pixelreco.AppendGif(animated, draw(testError))
+	fmt.Printf("TEST RSME: %.8f Count: %d\n", testError.RMSE(), testError.Count())
 	pixelreco.SaveGif(animated, "output")
 }
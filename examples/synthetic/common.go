@@ -0,0 +1,21 @@
+package synthetic
+
+import (
+	"flag"
+
+	"github.com/lovoo/cofire/synthetic"
+)
+
+// GeneratorFlags defines the flags of a generator.
+func GeneratorFlags() *synthetic.Generator {
+	g := new(synthetic.Generator)
+	flag.IntVar(&g.Users, "users", 1000, "number of users")
+	flag.IntVar(&g.Products, "products", 500, "number of products")
+	flag.IntVar(&g.Rank, "true-rank", 5, "rank of the ground-truth features")
+	flag.Float64Var(&g.Density, "density", 0.1, "fraction of the products rated by each user")
+	flag.Float64Var(&g.Skew, "skew", 1, "Zipf exponent of the product popularity, 0 for uniform")
+	flag.Float64Var(&g.Noise, "noise", 0.1, "standard deviation of the noise of the scores")
+	flag.Float64Var(&g.Bias, "bias", 3, "global bias of the scores")
+	flag.Int64Var(&g.Seed, "seed", 1, "seed of the generator")
+	return g
+}
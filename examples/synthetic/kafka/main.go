@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/cofire/examples"
+	"github.com/lovoo/cofire/examples/synthetic"
+	"github.com/lovoo/goka"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	generator   = synthetic.GeneratorFlags()
+	group       = flag.String("group", "cofire-synthetic", "consumer group for learner")
+	broker      = flag.String("broker", "localhost:9092", "a bootstrap Kafka broker")
+	sample      = flag.Int("sample", 80, "percentage of the generated ratings used for training")
+	gamma       = flag.Float64("gamma", 0.01, "SGD gamma parameter")
+	lambda      = flag.Float64("lambda", 0.001, "SGD lambda parameter")
+	rank        = flag.Int("rank", 5, "number of latent features")
+	iterations  = flag.Int("iterations", 5, "number of iterations")
+	delay       = flag.Duration("delay", time.Second, "reiteration delay")
+	metricsAddr = flag.String("metrics", ":9090", "address serving the Prometheus metrics")
+)
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	var (
+		brokers = []string{*broker}
+		ggroup  = goka.Group(*group)
+		ctx     = context.Background()
+		params  = cofire.Parameters{
+			Gamma:      *gamma,
+			Lambda:     *lambda,
+			Rank:       *rank,
+			Iterations: *iterations,
+		}
+	)
+
+	d, err := generator.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	train, test := (&cofire.RandomSplitter{TestFraction: float64(100-*sample) / 100, Seed: generator.Seed}).Split(d.Ratings)
+	fmt.Printf("Train set: %d\n", len(train))
+	fmt.Printf("Test  set: %d\n", len(test))
+	fmt.Printf("Noise floor: %.8f\n", d.NoiseFloor(test))
+
+	grp, ctx := errgroup.WithContext(ctx)
+	metrics := cofire.NewMetrics(ggroup)
+	grp.Go(examples.StartMetrics(ctx, *metricsAddr, metrics))
+	grp.Go(examples.StartLearner(ctx, brokers, ggroup, params, metrics))
+	grp.Go(examples.StartProducer(ctx, brokers, ggroup, train))
+	grp.Go(examples.StartRefeeder(ctx, brokers, ggroup, *delay, metrics))
+	view, startView := examples.CreateView(brokers, ggroup)
+	grp.Go(startView(ctx))
+	grp.Go(examples.StartValidator(ctx, view, test, params, metrics))
+
+	if err := grp.Wait(); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/lovoo/cofire"
+	"github.com/lovoo/cofire/examples/synthetic"
+	gen "github.com/lovoo/cofire/synthetic"
+)
+
+var (
+	generator  = synthetic.GeneratorFlags()
+	sample     = flag.Int("sample", 80, "percentage of the generated ratings used for training")
+	gamma      = flag.Float64("gamma", 0.01, "SGD gamma parameter")
+	lambda     = flag.Float64("lambda", 0.001, "SGD lambda parameter")
+	rank       = flag.Int("rank", 5, "number of latent features")
+	iterations = flag.Int("iterations", 50, "number of iterations")
+	workers    = flag.Int("workers", 1, "number of training goroutines")
+)
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	d, err := generator.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	train, test := (&cofire.RandomSplitter{TestFraction: float64(100-*sample) / 100, Seed: generator.Seed}).Split(d.Ratings)
+	fmt.Printf("Train set: %d\n", len(train))
+	fmt.Printf("Test  set: %d\n", len(test))
+
+	params := cofire.Parameters{
+		Gamma:      *gamma,
+		Lambda:     *lambda,
+		Rank:       *rank,
+		Iterations: *iterations,
+	}
+	trainer := cofire.NewLocalTrainer(nil, params, cofire.WithWorkers(*workers, false))
+	trainer.Train(train)
+
+	for _, set := range []struct {
+		name    string
+		ratings []cofire.Rating
+	}{{"TRAIN", train}, {"TEST", test}} {
+		rmse, n := gen.RMSE(trainer.Model(), trainer.Bias(), set.ratings)
+		fmt.Printf("%s RSME: %.8f Count: %d Noise floor: %.8f\n", set.name, rmse, n, d.NoiseFloor(set.ratings))
+	}
+}
@@ -15,6 +15,7 @@ func (f *Features) clone() *Features {
 	o := NewFeatures(len(f.V))
 	copy(o.V, f.V)
 	o.Bias = f.Bias
+	o.Updates = f.Updates
 	return o
 }
 
@@ -26,36 +27,59 @@ func (f *Features) Randomize() *Features {
 	return f
 }
 
-// predict predicts a r^ for a given user and a product
+// dot returns the dot product of the features, summing in order, so that
+// the result does not depend on the unrolling.
 func (f *Features) dot(o *Features) float64 {
-	score := 0.0
-	n := len(f.V)
-	if len(o.V) < n {
-		n = len(o.V)
+	var (
+		x     = f.V
+		y     = o.V
+		score float64
+	)
+	if len(y) < len(x) {
+		x = x[:len(y)]
 	}
-	for i := 0; i < n; i++ {
-		score += f.V[i] * o.V[i]
+	y = y[:len(x)]
+	i := 0
+	for ; i <= len(x)-4; i += 4 {
+		score += x[i] * y[i]
+		score += x[i+1] * y[i+1]
+		score += x[i+2] * y[i+2]
+		score += x[i+3] * y[i+3]
+	}
+	for ; i < len(x); i++ {
+		score += x[i] * y[i]
 	}
 	return score
 }
 
-// mult multiplies the features by a scalar and returns the resulting vector
-func (f *Features) mult(scalar float64) []float64 {
-	r := make([]float64, len(f.V))
-	for i := range f.V {
-		r[i] = f.V[i] * scalar
+// axpby updates the features in place to f + a*o + b*f, where o only
+// contributes to the features of f it has. It computes the same values as
+// adding a*o and then b*f to f without allocating.
+func (f *Features) axpby(a float64, o *Features, b float64) {
+	var (
+		x = f.V
+		y = o.V
+		n = len(x)
+	)
+	if len(y) < n {
+		n = len(y)
 	}
-	return r
-}
-
-// add adds a vector to the features
-func (f *Features) add(v []float64) {
-	n := len(f.V)
-	if len(v) < n {
-		n = len(v)
+	y = y[:n]
+	i := 0
+	for ; i <= n-4; i += 4 {
+		v0, v1, v2, v3 := x[i], x[i+1], x[i+2], x[i+3]
+		x[i] = v0 + y[i]*a + v0*b
+		x[i+1] = v1 + y[i+1]*a + v1*b
+		x[i+2] = v2 + y[i+2]*a + v2*b
+		x[i+3] = v3 + y[i+3]*a + v3*b
+	}
+	for ; i < n; i++ {
+		v := x[i]
+		x[i] = v + y[i]*a + v*b
 	}
-	for i := 0; i < n; i++ {
-		f.V[i] += v[i]
+	for ; i < len(x); i++ {
+		v := x[i]
+		x[i] = v + v*b
 	}
 }
 
@@ -1,6 +1,8 @@
 package cofire
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -22,3 +24,87 @@ func TestDot(t *testing.T) {
 		t.Errorf("score: %f, expected: 10.0", score)
 	}
 }
+
+// applyErrorReference is the allocating update ApplyError used to compute.
+func applyErrorReference(s *SGD, f, o *Features, e float64) {
+	update := make([]float64, len(o.V))
+	for i := range o.V {
+		update[i] = o.V[i] * (e * s.Gamma)
+	}
+	regularization := make([]float64, len(f.V))
+	for i := range f.V {
+		regularization[i] = f.V[i] * (-s.Lambda * s.Gamma)
+	}
+	for _, v := range [][]float64{update, regularization} {
+		for i := 0; i < len(f.V) && i < len(v); i++ {
+			f.V[i] += v[i]
+		}
+	}
+	f.Bias += s.Gamma * (e - s.Lambda*f.Bias)
+	f.Updates++
+}
+
+func TestAxpby(t *testing.T) {
+	s := NewSGD(0.01, 0.1)
+	for _, ranks := range [][2]int{{1, 1}, {3, 3}, {4, 4}, {10, 10}, {13, 13}, {10, 7}, {7, 10}} {
+		f := NewFeatures(ranks[0]).Randomize()
+		o := NewFeatures(ranks[1]).Randomize()
+		ref := f.clone()
+
+		s.ApplyError(f, o, 0.7)
+		applyErrorReference(s, ref, o, 0.7)
+		if !reflect.DeepEqual(f, ref) {
+			t.Errorf("ranks %v: expected %v, got %v", ranks, ref.V, f.V)
+		}
+	}
+}
+
+func TestApplyError_Allocs(t *testing.T) {
+	var (
+		s = NewSGD(0.01, 0.1)
+		f = NewFeatures(128).Randomize()
+		o = NewFeatures(128).Randomize()
+	)
+	if n := testing.AllocsPerRun(100, func() { s.ApplyError(f, o, 0.1) }); n != 0 {
+		t.Errorf("ApplyError allocates %f times", n)
+	}
+}
+
+var benchRanks = []int{10, 32, 64, 128, 256}
+
+func BenchmarkApplyError(b *testing.B) {
+	for _, rank := range benchRanks {
+		var (
+			s = NewSGD(0.001, 0.01)
+			f = NewFeatures(rank).Randomize()
+			o = NewFeatures(rank).Randomize()
+		)
+		b.Run(fmt.Sprintf("rank=%d", rank), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.ApplyError(f, o, 0.1)
+			}
+		})
+		b.Run(fmt.Sprintf("rank=%d/reference", rank), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				applyErrorReference(s, f, o, 0.1)
+			}
+		})
+	}
+}
+
+func BenchmarkDot(b *testing.B) {
+	for _, rank := range benchRanks {
+		var (
+			f = NewFeatures(rank).Randomize()
+			o = NewFeatures(rank).Randomize()
+		)
+		b.Run(fmt.Sprintf("rank=%d", rank), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.dot(o)
+			}
+		})
+	}
+}
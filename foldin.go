@@ -0,0 +1,118 @@
+package cofire
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// FoldIn computes the U features of a user from a set of ratings without
+// training the model. The user ID of the ratings is ignored. FoldIn solves the
+// regularized least squares problem
+//
+//	min sum (score - bias - p.Bias - u.Bias - u*p)^2 + lambda*(|u|^2 + u.Bias^2)
+//
+// against the P features of the rated products, using params.Rank and
+// params.Lambda, which must not be negative. Ratings of products without P
+// features are skipped. If no rated product has P features, FoldIn returns nil.
+func FoldIn(m Model, ratings []*Rating, params Parameters, bias float64) (*Features, error) {
+	if params.Lambda < 0 {
+		return nil, fmt.Errorf("negative lambda %v", params.Lambda)
+	}
+	var (
+		n = params.Rank + 1 // features plus user bias
+		a = make([]float64, n*n)
+		b = make([]float64, n)
+		x = make([]float64, n)
+		k int
+	)
+	for _, r := range ratings {
+		e, err := m.Get(r.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		p := e.GetP()
+		if p.Rank() != params.Rank {
+			continue
+		}
+		k++
+
+		// row of the design matrix is (p, 1)
+		copy(x, p.V)
+		x[n-1] = 1
+		y := r.Score - bias - p.Bias
+		for i := 0; i < n; i++ {
+			b[i] += x[i] * y
+			for j := 0; j <= i; j++ {
+				a[i*n+j] += x[i] * x[j]
+			}
+		}
+	}
+	if k == 0 {
+		return nil, nil
+	}
+
+	for i := 0; i < n; i++ {
+		a[i*n+i] += params.Lambda
+	}
+	if err := solveCholesky(a, b, n); err != nil {
+		return nil, err
+	}
+
+	u := NewFeatures(params.Rank)
+	copy(u.V, b[:params.Rank])
+	u.Bias = b[n-1]
+	return u, nil
+}
+
+var errNotPositiveDefinite = errors.New("matrix is not positive definite")
+
+// choleskyEpsilon is the fraction of a diagonal element of the matrix below
+// which its pivot is considered zero, as rounding errors keep the pivots of
+// singular matrices from reaching zero exactly.
+const choleskyEpsilon = 1e-10
+
+// solveCholesky solves a*x = b in place for the symmetric positive definite
+// n-by-n matrix a, of which only the lower triangle is used. The solution is
+// stored in b, and a is overwritten by its Cholesky factor.
+func solveCholesky(a, b []float64, n int) error {
+	// factorize a = l*l^T
+	for j := 0; j < n; j++ {
+		d := a[j*n+j]
+		min := choleskyEpsilon * d
+		for k := 0; k < j; k++ {
+			d -= a[j*n+k] * a[j*n+k]
+		}
+		if d <= 0 || d <= min {
+			return errNotPositiveDefinite
+		}
+		d = math.Sqrt(d)
+		a[j*n+j] = d
+		for i := j + 1; i < n; i++ {
+			s := a[i*n+j]
+			for k := 0; k < j; k++ {
+				s -= a[i*n+k] * a[j*n+k]
+			}
+			a[i*n+j] = s / d
+		}
+	}
+
+	// forward substitution l*y = b
+	for i := 0; i < n; i++ {
+		s := b[i]
+		for k := 0; k < i; k++ {
+			s -= a[i*n+k] * b[k]
+		}
+		b[i] = s / a[i*n+i]
+	}
+
+	// backward substitution l^T*x = y
+	for i := n - 1; i >= 0; i-- {
+		s := b[i]
+		for k := i + 1; k < n; k++ {
+			s -= a[k*n+i] * b[k]
+		}
+		b[i] = s / a[i*n+i]
+	}
+	return nil
+}
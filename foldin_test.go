@@ -0,0 +1,103 @@
+package cofire
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFoldIn(t *testing.T) {
+	var (
+		params  = Parameters{Rank: 3, Lambda: 0.0001}
+		bias    = 2.0
+		model   = make(MemoryModel)
+		u       = &Features{V: []float64{0.5, -1.0, 2.0}, Bias: 0.25}
+		ratings []*Rating
+	)
+	for i := 0; i < 20; i++ {
+		p := NewFeatures(params.Rank).Randomize()
+		p.Bias = float64(i%3) / 10
+		id := fmt.Sprintf("product-%d", i)
+		model[id] = &Entry{P: p}
+		ratings = append(ratings, &Rating{ProductId: id, Score: u.Predict(p, bias)})
+	}
+	// ratings of unknown products are ignored
+	ratings = append(ratings, &Rating{ProductId: "unknown", Score: 100})
+
+	f, err := FoldIn(model, ratings, params, bias)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range u.V {
+		if math.Abs(f.V[i]-u.V[i]) > 0.01 {
+			t.Errorf("feature %d: %f, expected: %f", i, f.V[i], u.V[i])
+		}
+	}
+	if math.Abs(f.Bias-u.Bias) > 0.01 {
+		t.Errorf("bias: %f, expected: %f", f.Bias, u.Bias)
+	}
+
+	f, err = FoldIn(model, []*Rating{{ProductId: "unknown", Score: 1}}, params, bias)
+	if err != nil || f != nil {
+		t.Errorf("expected no features, got: %v, %v", f, err)
+	}
+}
+
+func TestFoldIn_Singular(t *testing.T) {
+	var (
+		params = Parameters{Rank: 1}
+		model  = MemoryModel{"prod": &Entry{P: &Features{V: []float64{0.1}}}}
+	)
+	// ratings of a single product do not determine the user bias, which
+	// rounding errors hide from an exact zero test
+	ratings := []*Rating{{ProductId: "prod"}, {ProductId: "prod"}, {ProductId: "prod"}}
+	if _, err := FoldIn(model, ratings, params, 0); err != errNotPositiveDefinite {
+		t.Errorf("expected %v, got: %v", errNotPositiveDefinite, err)
+	}
+
+	params.Lambda = -1
+	if _, err := FoldIn(model, ratings, params, 0); err == nil {
+		t.Errorf("expected error for negative lambda")
+	}
+}
+
+func TestPredictor_FoldIn(t *testing.T) {
+	client, stop := startPredictor(t, testModel())
+	defer stop()
+
+	// anonymous user likes prod3 and dislikes prod1
+	resp, err := client.Recommend(context.Background(), &RecommendRequest{
+		K:            1,
+		IncludeRated: true,
+		Ratings: []*Rating{
+			{ProductId: "prod1", Score: -1},
+			{ProductId: "prod3", Score: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Recommendations) != 1 {
+		t.Fatalf("expected 1 recommendation, got %d", len(resp.Recommendations))
+	}
+	equals(t, resp.Recommendations[0].ProductId, "prod3")
+}
+
+func TestPredictor_FoldInSingular(t *testing.T) {
+	params := DefaultParams()
+	params.Lambda = 0
+	client, stop := startPredictor(t, testModel(), WithParams(params))
+	defer stop()
+
+	// a single rating without regularization does not determine U
+	_, err := client.FoldIn(context.Background(), &FoldInRequest{
+		Ratings: []*Rating{{ProductId: "prod1", Score: 1}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got: %v", err)
+	}
+}
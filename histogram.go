@@ -0,0 +1,60 @@
+package cofire
+
+import (
+	"sort"
+	"sync"
+)
+
+// HistogramValidator validates each prediction counting the residuals, ie,
+// score - prediction, in buckets.
+type HistogramValidator struct {
+	bounds []float64
+	counts []uint64
+	m      sync.RWMutex
+}
+
+// NewHistogramValidator creates a HistogramValidator with buckets of the given
+// sorted upper bounds. Residuals larger than the last bound are counted in an
+// additional bucket.
+func NewHistogramValidator(bounds ...float64) *HistogramValidator {
+	return &HistogramValidator{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// LinearBounds returns n bucket bounds starting at start with the given width.
+func LinearBounds(start, width float64, n int) []float64 {
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = start + float64(i)*width
+	}
+	return bounds
+}
+
+// Validate validates the prediction given a score.
+func (h *HistogramValidator) Validate(prediction, score float64) {
+	i := sort.SearchFloat64s(h.bounds, score-prediction)
+	h.m.Lock()
+	h.counts[i]++
+	h.m.Unlock()
+}
+
+// Buckets returns the upper bounds of the buckets and the number of residuals
+// counted in each bucket. The last count has no upper bound.
+func (h *HistogramValidator) Buckets() ([]float64, []uint64) {
+	h.m.RLock()
+	defer h.m.RUnlock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return h.bounds, counts
+}
+
+// Reset resets the counts of the HistogramValidator.
+func (h *HistogramValidator) Reset() {
+	h.m.Lock()
+	defer h.m.Unlock()
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+}
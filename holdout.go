@@ -0,0 +1,75 @@
+package cofire
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// WithHoldout makes the learner hold out a fraction of the incoming ratings
+// for evaluation. Held-out ratings are predicted in the PRODUCT stage and
+// passed to v, but they are never learned, nor are they kept as rated
+// products. Whether a rating is held out is decided by hashing its user and
+// product, so a rating is held out no matter when or how often it is
+// received.
+func WithHoldout(fraction float64, v ContextValidator) LearnerOption {
+	return func(l *Learner) {
+		l.holdout = fraction
+		l.holdoutV = v
+	}
+}
+
+// isHoldout returns whether the rating is held out.
+func (l *Learner) isHoldout(r *Rating) bool {
+	return l.holdout > 0 && Holdout(r, l.holdout)
+}
+
+// Holdout returns whether a rating is in the held-out fraction of the
+// ratings. The decision only depends on the user and product of the rating.
+func Holdout(r *Rating, fraction float64) bool {
+	h := fnv.New32a()
+	h.Write([]byte(r.UserId))
+	h.Write([]byte{0})
+	h.Write([]byte(r.ProductId))
+	return float64(h.Sum32()) < fraction*(math.MaxUint32+1.0)
+}
+
+// HoldoutRecorder is a ContextValidator keeping the most recent ratings it
+// validates, eg, to evaluate the ranking of held-out ratings with
+// RankingEvaluator.
+type HoldoutRecorder struct {
+	ratings []Rating
+	next    int
+	full    bool
+	m       sync.RWMutex
+}
+
+// NewHoldoutRecorder creates a HoldoutRecorder keeping at most max ratings.
+func NewHoldoutRecorder(max int) *HoldoutRecorder {
+	return &HoldoutRecorder{ratings: make([]Rating, max)}
+}
+
+// ValidateContext records the rating of the validation.
+func (h *HoldoutRecorder) ValidateContext(v *Validation) {
+	if len(h.ratings) == 0 {
+		return
+	}
+	h.m.Lock()
+	h.ratings[h.next] = *v.Rating
+	h.next++
+	if h.next == len(h.ratings) {
+		h.next = 0
+		h.full = true
+	}
+	h.m.Unlock()
+}
+
+// Ratings returns the recorded ratings, oldest first.
+func (h *HoldoutRecorder) Ratings() []Rating {
+	h.m.RLock()
+	defer h.m.RUnlock()
+	if !h.full {
+		return append([]Rating(nil), h.ratings[:h.next]...)
+	}
+	return append(append([]Rating(nil), h.ratings[h.next:]...), h.ratings[:h.next]...)
+}
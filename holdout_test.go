@@ -0,0 +1,87 @@
+package cofire
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHoldout(t *testing.T) {
+	var (
+		n     = 10000
+		count int
+	)
+	for i := 0; i < n; i++ {
+		r := &Rating{UserId: fmt.Sprintf("user%d", i%100), ProductId: fmt.Sprintf("prod%d", i/100)}
+		h := Holdout(r, 0.2)
+		if h != Holdout(r, 0.2) {
+			t.Fatalf("holdout of %v not deterministic", r)
+		}
+		if h && !Holdout(r, 0.5) {
+			t.Errorf("rating %v held out with fraction 0.2 but not with 0.5", r)
+		}
+		if h {
+			count++
+		}
+	}
+	if f := float64(count) / float64(n); f < 0.18 || f > 0.22 {
+		t.Errorf("unexpected holdout fraction: %f", f)
+	}
+	if Holdout(&Rating{UserId: "user", ProductId: "prod"}, 0) {
+		t.Errorf("rating held out with fraction 0")
+	}
+	if !Holdout(&Rating{UserId: "user", ProductId: "prod"}, 1) {
+		t.Errorf("rating not held out with fraction 1")
+	}
+}
+
+func TestLearner_Holdout(t *testing.T) {
+	var (
+		params   = DefaultParams()
+		train    = make(segmentValidator)
+		recorder = NewHoldoutRecorder(10)
+		l        = newLearner("test", nil, params)
+		stages   = l.stages("refeed")
+	)
+	WithContextValidator(train)(l)
+	WithHoldout(1, recorder)(l)
+	WithRatedItems(10, 0)(l)
+
+	p := NewFeatures(params.Rank).Randomize()
+	ctx := &mockContext{ts: time.Now(), value: &Entry{P: p.clone()}}
+	r := &Rating{UserId: "user", ProductId: "prod", Score: 1}
+	stages(ctx, &Message{Stage: Stage_PRODUCT, Rating: r, F: NewFeatures(params.Rank).Randomize(), Iters: 1})
+
+	if len(train) != 0 {
+		t.Errorf("held-out rating validated as training rating")
+	}
+	if ratings := recorder.Ratings(); len(ratings) != 1 || ratings[0].ProductId != "prod" {
+		t.Errorf("unexpected held-out ratings: %v", ratings)
+	}
+	if !reflect.DeepEqual(ctx.value.(*Entry).P, p) {
+		t.Errorf("held-out rating was learned")
+	}
+
+	ctx = &mockContext{ts: time.Now()}
+	l.entry(ctx, r)
+	if e := ctx.value.(*Entry); e.HasRated("prod") {
+		t.Errorf("held-out rating kept as rated product")
+	}
+}
+
+func TestHoldoutRecorder(t *testing.T) {
+	h := NewHoldoutRecorder(3)
+	for i := 0; i < 5; i++ {
+		h.ValidateContext(&Validation{Rating: &Rating{ProductId: fmt.Sprint(i)}})
+	}
+	ratings := h.Ratings()
+	if len(ratings) != 3 {
+		t.Fatalf("unexpected number of ratings: %d", len(ratings))
+	}
+	for i, r := range ratings {
+		if r.ProductId != fmt.Sprint(i+2) {
+			t.Errorf("unexpected rating %d: %v", i, r)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package cofire
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+// rateTau is the time constant of the decay of Rate, so that its value
+// approximates the events of the last seconds per second.
+const rateTau = time.Second
+
+// at returns the rate at time ts in unix milliseconds.
+func (r *Rate) at(ts int64) float64 {
+	if r == nil {
+		return 0
+	}
+	dt := ts - r.Time
+	if dt < 0 {
+		dt = 0
+	}
+	return r.Value * math.Exp(-float64(dt)*float64(time.Millisecond)/float64(rateTau))
+}
+
+// add records an event at time ts in unix milliseconds and returns the rate.
+func (r *Rate) add(ts int64) float64 {
+	r.Value = r.at(ts) + float64(time.Second)/float64(rateTau)
+	if ts > r.Time {
+		r.Time = ts
+	}
+	return r.Value
+}
+
+// hotStaleAfter is the time after which a batch whose FLUSH message did not
+// return is considered lost in a rebalance.
+const hotStaleAfter = time.Minute
+
+// WithHotProducts makes the learner batch the PRODUCT stage of hot products,
+// ie, of products receiving more than threshold messages per second. Instead
+// of learning and storing P once per message, the messages of a hot product
+// are kept in memory for window, at least 1ms. Then they are validated, the
+// sum of their gradients is applied to P in one update, the entry is stored
+// once, and the resulting P is sent to every waiting user. A batch is flushed
+// by the first message of the product after the window, or by a FLUSH message
+// that the next message of any key loops back to the product. The learner
+// checks for due batches at most once per window.
+//
+// Batches are not persisted, so the remaining iterations of the messages of
+// at most one window per hot product are lost if the processor fails or its
+// partitions are rebalanced. Batches whose FLUSH message does not return
+// within a minute are dropped. LocalTrainer ignores this option.
+func WithHotProducts(threshold float64, window time.Duration) LearnerOption {
+	if window < time.Millisecond {
+		window = time.Millisecond
+	}
+	return func(l *Learner) {
+		l.hot = &hotProducts{
+			threshold: threshold,
+			window:    int64(window / time.Millisecond),
+			batches:   make(map[string]*batch),
+			now:       time.Now,
+		}
+	}
+}
+
+// hotProducts keeps the batches of the hot products of the learner's
+// partitions.
+type hotProducts struct {
+	threshold float64
+	window    int64 // in milliseconds
+	now       func() time.Time
+
+	m       sync.Mutex
+	batches map[string]*batch
+	checked int64 // last check for due batches in unix milliseconds
+}
+
+// batch holds the PRODUCT messages of a hot product waiting for P. Batches
+// are only accessed with the mutex of hotProducts held.
+type batch struct {
+	rate      *Rate
+	since     int64 // arrival of the first message in unix milliseconds
+	msgs      []*Message
+	scheduled time.Time // when the FLUSH message was looped back, if so
+}
+
+// due takes the messages of the batch and a copy of its rate if the window of
+// the batch passed at now.
+func (b *batch) due(now, window int64) ([]*Message, *Rate) {
+	if len(b.msgs) == 0 || now-b.since < window {
+		return nil, nil
+	}
+	msgs := b.msgs
+	b.msgs, b.since, b.scheduled = nil, 0, time.Time{}
+	return msgs, &Rate{Value: b.rate.Value, Time: b.rate.Time}
+}
+
+// millis returns t in unix milliseconds.
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// batchProduct adds msg to the batch of the product entry e if the product
+// is hot, and flushes the batch if its window passed. It returns false if msg
+// has to be learned right away.
+func (l *Learner) batchProduct(ctx goka.Context, e *Entry, msg *Message) bool {
+	var (
+		h   = l.hot
+		key = ctx.Key()
+		now = millis(ctx.Timestamp())
+	)
+	batched, msgs, rate := func() (bool, []*Message, *Rate) {
+		h.m.Lock()
+		defer h.m.Unlock()
+		b := h.batches[key]
+		switch {
+		case b == nil:
+			// the rate is stored with the entry while the product is not hot
+			if e.ProductRate == nil {
+				e.ProductRate = new(Rate)
+			}
+			if e.ProductRate.add(now) < h.threshold {
+				return false, nil, nil
+			}
+			b = &batch{rate: &Rate{Value: e.ProductRate.Value, Time: e.ProductRate.Time}}
+			h.batches[key] = b
+			l.metrics.hot(1)
+		case b.rate.add(now) < h.threshold && len(b.msgs) == 0:
+			// cooled down
+			e.ProductRate = b.rate
+			delete(h.batches, key)
+			l.metrics.hot(-1)
+			return false, nil, nil
+		}
+
+		if l.isHoldout(msg.Rating) {
+			return false, nil, nil
+		}
+		if len(b.msgs) == 0 {
+			b.since = now
+		}
+		b.msgs = append(b.msgs, msg)
+		msgs, rate := b.due(now, h.window)
+		return true, msgs, rate
+	}()
+	if msgs != nil {
+		l.flush(ctx, e, msgs, rate)
+	}
+	return batched
+}
+
+// scheduleFlushes loops a FLUSH message back to every hot product whose batch
+// is due, at most once per window. It also drops empty batches of products
+// that cooled down and batches whose FLUSH message got lost.
+func (l *Learner) scheduleFlushes(ctx goka.Context) {
+	var (
+		h     = l.hot
+		now   = millis(ctx.Timestamp())
+		flush = make(map[string]int64)
+	)
+	h.m.Lock()
+	if now-h.checked < h.window {
+		h.m.Unlock()
+		return
+	}
+	h.checked = now
+	for key, b := range h.batches {
+		switch {
+		case len(b.msgs) == 0:
+			if b.rate.at(now) < h.threshold {
+				delete(h.batches, key)
+				l.metrics.hot(-1)
+			}
+		case !b.scheduled.IsZero():
+			if h.now().Sub(b.scheduled) > hotStaleAfter {
+				// lost in a rebalance
+				delete(h.batches, key)
+				l.metrics.hot(-1)
+			}
+		case now-b.since >= h.window:
+			b.scheduled = h.now()
+			flush[key] = b.since
+		}
+	}
+	h.m.Unlock()
+
+	for key, since := range flush {
+		ctx.Loopback(key, &Message{Stage: Stage_FLUSH, Timestamp: since})
+	}
+}
+
+// flushProduct flushes the batch of the product entry e when its FLUSH
+// message returns.
+func (l *Learner) flushProduct(ctx goka.Context, e *Entry, msg *Message) {
+	if l.hot == nil {
+		return
+	}
+	var (
+		h    = l.hot
+		msgs []*Message
+		rate *Rate
+	)
+	h.m.Lock()
+	if b := h.batches[ctx.Key()]; b != nil && b.since == msg.Timestamp {
+		if msgs, rate = b.due(millis(ctx.Timestamp()), h.window); msgs == nil {
+			// too early, the next check schedules it again
+			b.scheduled = time.Time{}
+		}
+	}
+	h.m.Unlock()
+	if msgs != nil {
+		l.flush(ctx, e, msgs, rate)
+	}
+}
+
+// flush validates the batched messages with P of the product entry e,
+// applies the sum of their gradients to P, stores the entry with the rate of
+// the product and sends P to the users of the messages.
+func (l *Learner) flush(ctx goka.Context, e *Entry, msgs []*Message, rate *Rate) {
+	var (
+		ts = ctx.Timestamp()
+		fs = make([]*Features, len(msgs))
+		es = make([]float64, len(msgs))
+	)
+	l.initP(e)
+	for _, msg := range msgs {
+		l.sgd.Add(msg.Rating.Score)
+	}
+	bias := l.sgd.Bias()
+	for i, msg := range msgs {
+		prediction := e.P.Predict(msg.F, bias)
+		if l.v != nil {
+			validatePrediction(l.v, msg, prediction, ts)
+		}
+		fs[i], es[i] = msg.F, msg.Rating.Score-prediction
+	}
+	l.sgd.ApplyErrors(e.P, fs, es)
+	e.ProductRate = rate
+	setEntry(ctx, e)
+	l.metrics.hotBatch(len(msgs))
+
+	for _, msg := range msgs {
+		msg.Stage = Stage_USER
+		msg.F = e.P
+		ctx.Loopback(msg.Rating.UserId, msg)
+	}
+}
@@ -0,0 +1,313 @@
+package cofire
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+// queueContext runs the learner's stages on an in-memory table, processing
+// loopbacks in order of arrival like a partition does. Its clock advances by
+// tick per processed message.
+type queueContext struct {
+	mockContext
+	key    string
+	table  MemoryModel
+	queue  []queued
+	stages goka.ProcessCallback
+	tick   time.Duration
+
+	// messages processed and entries stored per key
+	processed map[string][]*Message
+	stored    map[string]int
+}
+
+type queued struct {
+	key string
+	msg *Message
+}
+
+func newQueueContext(l *Learner, tick time.Duration) *queueContext {
+	return &queueContext{
+		mockContext: mockContext{ts: time.Unix(1000, 0)},
+		table:       make(MemoryModel),
+		stages:      l.stages("refeed"),
+		tick:        tick,
+		processed:   make(map[string][]*Message),
+		stored:      make(map[string]int),
+	}
+}
+
+func (c *queueContext) Key() string { return c.key }
+func (c *queueContext) SetValue(v interface{}) {
+	c.table[c.key] = v.(*Entry)
+	c.stored[c.key]++
+}
+func (c *queueContext) Value() interface{} {
+	if e, ok := c.table[c.key]; ok {
+		return e
+	}
+	return nil
+}
+func (c *queueContext) Emit(goka.Stream, string, interface{}) {}
+
+// Loopback queues a copy of the message, as encoding it would.
+func (c *queueContext) Loopback(key string, m interface{}) {
+	msg := *m.(*Message)
+	if msg.F != nil {
+		msg.F = msg.F.clone()
+	}
+	c.queue = append(c.queue, queued{key, &msg})
+}
+
+// run processes the queue until it is empty.
+func (c *queueContext) run() {
+	for len(c.queue) > 0 {
+		c.step()
+	}
+}
+
+// step processes the first message of the queue.
+func (c *queueContext) step() {
+	q := c.queue[0]
+	c.queue = c.queue[1:]
+	c.key = q.key
+	processed := *q.msg
+	c.processed[q.key] = append(c.processed[q.key], &processed)
+	c.stages(c, q.msg)
+	c.ts = c.ts.Add(c.tick)
+}
+
+// rate queues PRODUCT messages of n users rating the product at once.
+func (c *queueContext) rate(product string, n, rank int) {
+	for i := 0; i < n; i++ {
+		f := NewFeatures(rank)
+		for j := range f.V {
+			f.V[j] = float64(i+j) / 10
+		}
+		c.queue = append(c.queue, queued{product, &Message{
+			Stage:  Stage_PRODUCT,
+			Rating: &Rating{UserId: fmt.Sprintf("user%d", i), ProductId: product, Score: float64(i % 5)},
+			F:      f,
+			Iters:  1,
+		}})
+	}
+}
+
+// poke queues a message of an unrelated key, which the learner processes
+// like any other message.
+func (c *queueContext) poke() {
+	c.queue = append(c.queue, queued{"other", &Message{Stage: Stage_FLUSH}})
+}
+
+// flushes returns the number of FLUSH messages processed by a key.
+func (c *queueContext) flushes(key string) int {
+	var n int
+	for _, msg := range c.processed[key] {
+		if msg.Stage == Stage_FLUSH {
+			n++
+		}
+	}
+	return n
+}
+
+// userP returns the P features received by the users.
+func (c *queueContext) userP() map[string]*Features {
+	m := make(map[string]*Features)
+	for key, msgs := range c.processed {
+		for _, msg := range msgs {
+			if msg.Stage == Stage_USER {
+				m[key] = msg.F
+			}
+		}
+	}
+	return m
+}
+
+func TestHotProducts(t *testing.T) {
+	const n = 20
+	var (
+		params = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 1}
+		v      = make(iterValidator)
+		m      = NewMetrics("test")
+		l      = newLearner("test", nil, params)
+	)
+	WithContextValidator(v)(l)
+	WithHotProducts(2, 10*time.Millisecond)(l)
+	WithMetrics(m)(l)
+
+	p := &Features{V: []float64{0.1, 0.2, 0.3}}
+	ctx := newQueueContext(l, 0)
+	ctx.table["prod"] = &Entry{P: p.clone()}
+	ctx.rate("prod", n, params.Rank)
+	for i := 0; i < n; i++ {
+		ctx.step()
+	}
+
+	// the next message after the window schedules the flush
+	ctx.ts = ctx.ts.Add(10 * time.Millisecond)
+	ctx.run()
+
+	// the first message is learned right away, the others in one batch
+	if stored := ctx.stored["prod"]; stored != 2 {
+		t.Errorf("expected 2 stores of the product entry, got %d", stored)
+	}
+	if v[1].Count() != n {
+		t.Errorf("expected %d validations, got %d", n, v[1].Count())
+	}
+
+	// P learns every message, the batch in one update
+	e := ctx.table["prod"]
+	if e.P.Updates != n || reflect.DeepEqual(e.P.V, p.V) {
+		t.Errorf("expected P learned %d times, got %v", n, e.P)
+	}
+	if e.ProductRate.GetValue() < 2 {
+		t.Errorf("expected rate of at least 2, got %v", e.ProductRate)
+	}
+
+	// every waiting user gets the resulting P
+	users := ctx.userP()
+	if len(users) != n {
+		t.Fatalf("expected P sent to %d users, got %d", n, len(users))
+	}
+	for i := 1; i < n; i++ {
+		if f := users[fmt.Sprintf("user%d", i)]; !reflect.DeepEqual(f.V, e.P.V) {
+			t.Errorf("user%d: expected P %v, got %v", i, e.P.V, f.V)
+		}
+	}
+
+	body := scrape(t, m)
+	for _, s := range []string{
+		`cofire_hot_products{group="test"} 1`,
+		`cofire_hot_batch_size_count{group="test"} 1`,
+		fmt.Sprintf(`cofire_hot_batch_size_sum{group="test"} %d`, n-1),
+		`cofire_messages_total{group="test",stage="FLUSH"} 1`,
+		`cofire_messages_total{group="test",stage="PRODUCT"} 20`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("missing %q in metrics:\n%s", s, body)
+		}
+	}
+
+	// products cool down once the rate drops below the threshold
+	ctx.ts = ctx.ts.Add(10 * time.Second)
+	ctx.poke()
+	ctx.run()
+	if l.hot.batches["prod"] != nil {
+		t.Errorf("product still hot")
+	}
+	ctx.rate("prod", 1, params.Rank)
+	ctx.run()
+	if stored := ctx.stored["prod"]; stored != 3 {
+		t.Errorf("expected 3 stores of the product entry, got %d", stored)
+	}
+	if body := scrape(t, m); !strings.Contains(body, `cofire_hot_products{group="test"} 0`) {
+		t.Errorf("expected no hot products:\n%s", body)
+	}
+}
+
+func TestHotProducts_Window(t *testing.T) {
+	var (
+		params = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 1}
+		l      = newLearner("test", nil, params)
+		ctx    = newQueueContext(l, 10*time.Millisecond)
+	)
+	WithHotProducts(1, 100*time.Millisecond)(l)
+
+	// the batch waits for the window without looping messages back
+	ctx.rate("prod", 3, params.Rank)
+	ctx.run()
+	if e := ctx.table["prod"]; e != nil && e.P.Updates != 0 {
+		t.Errorf("expected no P updates, got %d", e.P.Updates)
+	}
+	for i := 0; i < 5; i++ {
+		ctx.poke()
+	}
+	ctx.run()
+	if n := ctx.flushes("prod"); n != 0 {
+		t.Errorf("expected no FLUSH messages before the window, got %d", n)
+	}
+
+	// the first message of any key after the window schedules one FLUSH
+	ctx.ts = ctx.ts.Add(100 * time.Millisecond)
+	ctx.poke()
+	ctx.poke()
+	ctx.run()
+	if n := ctx.flushes("prod"); n != 1 {
+		t.Errorf("expected 1 FLUSH message, got %d", n)
+	}
+	if users := ctx.userP(); len(users) != 3 {
+		t.Errorf("expected P sent to 3 users, got %d", len(users))
+	}
+	if e := ctx.table["prod"]; e.P.Updates != 3 {
+		t.Errorf("expected 3 P updates, got %d", e.P.Updates)
+	}
+
+	// messages arriving after the window flush the batch right away
+	ctx.rate("prod", 1, params.Rank)
+	ctx.step()
+	ctx.ts = ctx.ts.Add(time.Second)
+	ctx.rate("prod", 1, params.Rank)
+	ctx.step()
+	if e := ctx.table["prod"]; e.P.Updates != 5 {
+		t.Errorf("expected 5 P updates, got %d", e.P.Updates)
+	}
+}
+
+func TestHotProducts_Rebalance(t *testing.T) {
+	var (
+		params = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 1}
+		l      = newLearner("test", nil, params)
+		m      = NewMetrics("test")
+		ctx    = newQueueContext(l, 0)
+		now    = time.Unix(1000, 0)
+	)
+	WithHotProducts(1, 0)(l)
+	WithMetrics(m)(l)
+	l.hot.now = func() time.Time { return now }
+
+	// the FLUSH message of a batch is consumed by another instance
+	batch := func() {
+		ctx.rate("prod", 2, params.Rank)
+		ctx.run()
+		ctx.ts = ctx.ts.Add(time.Millisecond)
+		ctx.poke()
+		ctx.step()
+		if len(ctx.queue) != 1 || ctx.queue[0].msg.Stage != Stage_FLUSH {
+			t.Fatalf("expected FLUSH message, got %v", ctx.queue)
+		}
+		ctx.queue = nil
+	}
+	batch()
+
+	// the next message of the product flushes the stale batch
+	ctx.ts = ctx.ts.Add(time.Minute)
+	ctx.tick = time.Millisecond
+	ctx.rate("prod", 3, params.Rank)
+	ctx.run()
+	if e := ctx.table["prod"]; e.P.Updates != 5 {
+		t.Errorf("expected 5 P updates, got %d", e.P.Updates)
+	}
+	if users := ctx.userP(); len(users) != 3 {
+		t.Errorf("expected P sent to 3 users, got %d", len(users))
+	}
+
+	// without messages of the product, the stale batch is dropped
+	ctx.tick = 0
+	ctx.ts = ctx.ts.Add(time.Second)
+	batch()
+	ctx.ts = ctx.ts.Add(time.Second)
+	now = now.Add(2 * hotStaleAfter)
+	ctx.poke()
+	ctx.run()
+	if b := l.hot.batches["prod"]; b != nil {
+		t.Errorf("stale batch not dropped: %v", b)
+	}
+	if body := scrape(t, m); !strings.Contains(body, `cofire_hot_products{group="test"} 0`) {
+		t.Errorf("expected no hot products:\n%s", body)
+	}
+}
@@ -0,0 +1,160 @@
+package cofire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/jsonpb"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/lovoo/goka"
+)
+
+// JSONRatingCodec encodes and decodes ratings as JSON objects with the field
+// names of cofire.proto, eg, {"user_id": "u", "product_id": "p", "score": 1}.
+// Decoding also accepts the lowerCamelCase field names. If Strict, decoding
+// fails for unknown fields, missing user or product IDs and non-finite
+// scores.
+type JSONRatingCodec struct {
+	Strict bool
+}
+
+func (c *JSONRatingCodec) Encode(v interface{}) ([]byte, error) {
+	return encodeJSON(v)
+}
+
+func (c *JSONRatingCodec) Decode(b []byte) (interface{}, error) {
+	var v Rating
+	if err := decodeJSON(b, &v, c.Strict); err != nil {
+		return nil, err
+	}
+	if c.Strict {
+		return &v, validateRating(&v)
+	}
+	return &v, nil
+}
+
+// JSONUpdateCodec encodes and decodes updates as JSON objects, eg,
+// {"u": {"v": [0.1, 0.2], "bias": 0.3}}. If Strict, decoding fails for
+// unknown fields, updates without features and non-finite values.
+type JSONUpdateCodec struct {
+	Strict bool
+}
+
+func (c *JSONUpdateCodec) Encode(v interface{}) ([]byte, error) {
+	return encodeJSON(v)
+}
+
+func (c *JSONUpdateCodec) Decode(b []byte) (interface{}, error) {
+	var v Update
+	if err := decodeJSON(b, &v, c.Strict); err != nil {
+		return nil, err
+	}
+	v.unpack()
+	if c.Strict {
+		if v.U == nil && v.P == nil {
+			return nil, errors.New("update without features")
+		}
+		if err := validateFeatures(v.U, v.P); err != nil {
+			return nil, err
+		}
+	}
+	return &v, nil
+}
+
+// JSONEntryCodec encodes and decodes entries as JSON objects. If Strict,
+// decoding fails for unknown fields and non-finite values.
+type JSONEntryCodec struct {
+	Strict bool
+}
+
+func (c *JSONEntryCodec) Encode(v interface{}) ([]byte, error) {
+	return encodeJSON(v)
+}
+
+func (c *JSONEntryCodec) Decode(b []byte) (interface{}, error) {
+	var v Entry
+	if err := decodeJSON(b, &v, c.Strict); err != nil {
+		return nil, err
+	}
+	v.unpack()
+	if c.Strict {
+		return &v, validateFeatures(v.U, v.P)
+	}
+	return &v, nil
+}
+
+// SniffingCodec decodes both JSON and protobuf messages, eg, while producers
+// migrate from one format to the other. Messages starting with '{' are
+// decoded with JSON, all others with Proto, since no message of cofire.proto
+// encodes to a leading '{'. Messages are encoded with JSON if EncodeJSON is
+// set, otherwise with Proto.
+type SniffingCodec struct {
+	JSON       goka.Codec
+	Proto      goka.Codec
+	EncodeJSON bool
+}
+
+func (c *SniffingCodec) Encode(v interface{}) ([]byte, error) {
+	if c.EncodeJSON {
+		return c.JSON.Encode(v)
+	}
+	return c.Proto.Encode(v)
+}
+
+func (c *SniffingCodec) Decode(b []byte) (interface{}, error) {
+	if t := bytes.TrimLeft(b, " \t\r\n"); len(t) > 0 && t[0] == '{' {
+		return c.JSON.Decode(b)
+	}
+	return c.Proto.Decode(b)
+}
+
+func encodeJSON(v interface{}) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		m   = jsonpb.Marshaler{OrigName: true}
+	)
+	if err := m.Marshal(&buf, v.(proto.Message)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeJSON(b []byte, v proto.Message, strict bool) error {
+	u := jsonpb.Unmarshaler{AllowUnknownFields: !strict}
+	return u.Unmarshal(bytes.NewReader(b), v)
+}
+
+func validateRating(r *Rating) error {
+	switch {
+	case r.UserId == "":
+		return errors.New("rating without user_id")
+	case r.ProductId == "":
+		return errors.New("rating without product_id")
+	case !finite(r.Score):
+		return fmt.Errorf("invalid score: %v", r.Score)
+	}
+	return nil
+}
+
+func validateFeatures(fs ...*Features) error {
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		if !finite(f.Bias) {
+			return fmt.Errorf("invalid feature bias: %v", f.Bias)
+		}
+		for _, x := range f.V {
+			if !finite(x) {
+				return fmt.Errorf("invalid feature value: %v", x)
+			}
+		}
+	}
+	return nil
+}
+
+func finite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
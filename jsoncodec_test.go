@@ -0,0 +1,146 @@
+package cofire
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONRatingCodec(t *testing.T) {
+	c := &JSONRatingCodec{Strict: true}
+	r := &Rating{UserId: "user", ProductId: "prod", Score: 4.5, Timestamp: 1500}
+
+	b, err := c.Encode(r)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	equals(t, string(b), `{"user_id":"user","product_id":"prod","score":4.5,"timestamp":"1500"}`)
+	v, err := c.Decode(b)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(v, r) {
+		t.Errorf("expected %v, got %v", r, v)
+	}
+
+	// camel case and numeric timestamp
+	v, err = c.Decode([]byte(`{"userId": "user", "productId": "prod", "score": 4.5, "timestamp": 1500}`))
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(v, r) {
+		t.Errorf("expected %v, got %v", r, v)
+	}
+
+	for _, s := range []string{
+		`{"user_id": "user", "product_id": "prod", "score": 1, "other": 1}`,
+		`{"product_id": "prod", "score": 1}`,
+		`{"user_id": "user", "score": 1}`,
+		`{"user_id": "user", "product_id": "prod", "score": "NaN"}`,
+		`{"user_id": "user"`,
+	} {
+		if _, err := c.Decode([]byte(s)); err == nil {
+			t.Errorf("expected strict error decoding %s", s)
+		}
+	}
+
+	// non-strict accepts unknown fields
+	if _, err := new(JSONRatingCodec).Decode([]byte(`{"user_id": "user", "other": 1}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJSONUpdateCodec(t *testing.T) {
+	c := &JSONUpdateCodec{Strict: true}
+	u := &Update{U: &Features{V: []float64{0.5, 1}, Bias: 0.25}}
+
+	b, err := c.Encode(u)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	v, err := c.Decode(b)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(v, u) {
+		t.Errorf("expected %v, got %v", u, v)
+	}
+
+	for _, s := range []string{`{}`, `{"p": {"v": [1, "Infinity"]}}`, `{"u": {"w": [1]}}`} {
+		if _, err := c.Decode([]byte(s)); err == nil {
+			t.Errorf("expected strict error decoding %s", s)
+		}
+	}
+}
+
+func TestJSONEntryCodec(t *testing.T) {
+	c := new(JSONEntryCodec)
+	e := &Entry{U: &Features{V: []float64{1, 2}}, P: &Features{V: []float64{3, 4}, Bias: 1}}
+
+	b, err := c.Encode(e)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	v, err := c.Decode(b)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(v, e) {
+		t.Errorf("expected %v, got %v", e, v)
+	}
+}
+
+func TestSniffingCodec(t *testing.T) {
+	var (
+		c = &SniffingCodec{JSON: new(JSONRatingCodec), Proto: new(RatingCodec)}
+		r = &Rating{UserId: "user", ProductId: "prod", Score: 1}
+	)
+
+	pb, err := new(RatingCodec).Encode(r)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	js, err := new(JSONRatingCodec).Encode(r)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	for _, b := range [][]byte{pb, js, append([]byte("\n "), js...)} {
+		v, err := c.Decode(b)
+		if err != nil {
+			t.Fatalf("error decoding %q: %v", b, err)
+		}
+		if !reflect.DeepEqual(v, r) {
+			t.Errorf("expected %v, got %v", r, v)
+		}
+	}
+
+	b, err := c.Encode(r)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	equals(t, string(b), string(pb))
+	c.EncodeJSON = true
+	b, err = c.Encode(r)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	equals(t, string(b), string(js))
+}
+
+func TestLearner_Codecs(t *testing.T) {
+	l := newLearner("test", nil, DefaultParams())
+	if _, ok := l.inputCodec.(*RatingCodec); !ok {
+		t.Errorf("unexpected default input codec: %T", l.inputCodec)
+	}
+	WithInputCodec(new(JSONRatingCodec))(l)
+	WithUpdateCodec(new(JSONUpdateCodec))(l)
+	WithTableCodec(new(JSONEntryCodec))(l)
+	if _, ok := l.inputCodec.(*JSONRatingCodec); !ok {
+		t.Errorf("unexpected input codec: %T", l.inputCodec)
+	}
+	if _, ok := l.updateCodec.(*JSONUpdateCodec); !ok {
+		t.Errorf("unexpected update codec: %T", l.updateCodec)
+	}
+	if _, ok := l.tableCodec.(*JSONEntryCodec); !ok {
+		t.Errorf("unexpected table codec: %T", l.tableCodec)
+	}
+}
@@ -2,24 +2,30 @@ package cofire
 
 import (
 	fmt "fmt"
+	"math/rand"
+	"sync"
+	"time"
 
 	"github.com/lovoo/goka"
 )
 
 // NewLearner returns the GroupGraph for a learner processor.
-func NewLearner(group goka.Group, validator Validator, params Parameters) *goka.GroupGraph {
+func NewLearner(group goka.Group, validator Validator, params Parameters, opts ...LearnerOption) *goka.GroupGraph {
 	var (
 		input  = fmt.Sprintf("%s-input", group)
 		update = fmt.Sprintf("%s-update", group)
 		refeed = fmt.Sprintf("%s-refeed", group)
 	)
 	p := newLearner(string(group), validator, params)
+	for _, opt := range opts {
+		opt(p)
+	}
 	edges := []goka.Edge{
-		goka.Input(goka.Stream(input), new(RatingCodec), p.entry),
-		goka.Input(goka.Stream(update), new(UpdateCodec), p.update),
-		goka.Loop(new(messageCodec), p.stages(goka.Stream(refeed))),
-		goka.Persist(new(EntryCodec)),
-		goka.Output(goka.Stream(refeed), new(messageCodec)),
+		goka.Input(goka.Stream(input), p.inputCodec, p.entry),
+		goka.Input(goka.Stream(update), p.updateCodec, p.update),
+		goka.Loop(&messageCodec{p.precision}, p.stages(goka.Stream(refeed))),
+		goka.Persist(p.persistCodec()),
+		goka.Output(goka.Stream(refeed), &messageCodec{p.precision}),
 	}
 	return goka.DefineGroup(group, edges...)
 }
@@ -27,39 +33,141 @@ func NewLearner(group goka.Group, validator Validator, params Parameters) *goka.
 // Learner factorizes a user-prodcut rating matrix by learning latent features
 // for users and products.
 type Learner struct {
-	group  string
-	params Parameters
-	v      Validator
-	sgd    *SGD
+	group   string
+	params  Parameters
+	v       ContextValidator
+	sgd     *SGD
+	metrics *Metrics
+
+	// bounds of the rated products kept per user
+	ratedMax int
+	ratedAge time.Duration
+
+	// fraction of ratings held out for evaluation and its validator
+	holdout  float64
+	holdoutV ContextValidator
+
+	// codecs of the input, update and table topics and precision of the
+	// features in loop messages
+	inputCodec  goka.Codec
+	updateCodec goka.Codec
+	tableCodec  goka.Codec
+	precision   Precision
+
+	// random source of new features, if seeded
+	rnd      *rand.Rand
+	rndMutex sync.Mutex
+
+	// batches of hot products, if enabled
+	hot *hotProducts
+}
+
+// LearnerOption configures a learner.
+type LearnerOption func(*Learner)
+
+// WithContextValidator sets the validator of the learner, replacing the
+// validator passed to NewLearner. Use Validators to combine several
+// validators.
+func WithContextValidator(v ContextValidator) LearnerOption {
+	return func(l *Learner) {
+		l.v = v
+	}
 }
 
-// newLearner creates a new cofire learner.
+// WithRatedItems makes the learner keep the products rated by each user in the
+// user's entry, eg, to exclude them from recommendations. At most max products
+// rated within maxAge are kept. A zero max or maxAge disables the respective
+// bound, but at least one bound has to be set for the products to be kept.
+func WithRatedItems(max int, maxAge time.Duration) LearnerOption {
+	return func(l *Learner) {
+		l.ratedMax = max
+		l.ratedAge = maxAge
+	}
+}
+
+// WithInputCodec sets the codec of the ratings in the "<group>-input" topic,
+// eg, a JSONRatingCodec. The default is RatingCodec.
+func WithInputCodec(c goka.Codec) LearnerOption {
+	return func(l *Learner) {
+		l.inputCodec = c
+	}
+}
+
+// WithUpdateCodec sets the codec of the updates in the "<group>-update"
+// topic. The default is UpdateCodec.
+func WithUpdateCodec(c goka.Codec) LearnerOption {
+	return func(l *Learner) {
+		l.updateCodec = c
+	}
+}
+
+// WithTableCodec sets the codec of the entries in the group table. Views of
+// the table have to use the same codec. The default is EntryCodec.
+func WithTableCodec(c goka.Codec) LearnerOption {
+	return func(l *Learner) {
+		l.tableCodec = c
+	}
+}
+
+// WithPrecision makes the learner store features with precision p in the
+// group table and in loop messages. It applies to the default table codec and
+// to an EntryCodec set with WithTableCodec, independent of the order of the
+// options; other table codecs, such as JSONEntryCodec, store doubles. Entries
+// of any precision are decoded, so that an existing table migrates as its
+// entries are updated.
+func WithPrecision(p Precision) LearnerOption {
+	return func(l *Learner) {
+		l.precision = p
+	}
+}
+
+// WithSeed makes the learner initialize new features from a random source
+// seeded with seed, eg, for reproducible local training.
+func WithSeed(seed int64) LearnerOption {
+	return func(l *Learner) {
+		l.rnd = rand.New(rand.NewSource(seed))
+	}
+}
+
+// persistCodec returns the codec of the group table with the precision of the
+// learner applied to an EntryCodec.
+func (l *Learner) persistCodec() goka.Codec {
+	if c, ok := l.tableCodec.(*EntryCodec); ok && l.precision != Float64 && c.Precision != l.precision {
+		return &EntryCodec{Precision: l.precision}
+	}
+	return l.tableCodec
+}
+
+// newLearner creates a new cofire learner. If validator implements
+// ContextValidator, it validates every iteration, otherwise only the first one.
 func newLearner(group string, validator Validator, params Parameters) *Learner {
-	return &Learner{
-		group:  group,
-		params: params,
-		v:      validator,
-		sgd:    NewSGD(params.Gamma, params.Lambda),
+	l := &Learner{
+		group:       group,
+		params:      params,
+		sgd:         NewSGD(params.Gamma, params.Lambda),
+		inputCodec:  new(RatingCodec),
+		updateCodec: new(UpdateCodec),
+		tableCodec:  new(EntryCodec),
+	}
+	switch v := validator.(type) {
+	case ContextValidator:
+		l.v = v
+	case Validator:
+		l.v = AdaptValidator(v, params.Iterations)
 	}
+	return l
 }
 
 // entry receives a Rating message in initiates a learning iteration.
 func (l *Learner) entry(ctx goka.Context, m interface{}) {
-	msg := m.(*Rating)
 	e := getEntry(ctx)
-
-	if e.U.Rank() != l.params.Rank {
-		e.U = NewFeatures(l.params.Rank).Randomize()
+	msg, changed := l.enter(e, m.(*Rating), ctx.Timestamp())
+	if changed {
 		setEntry(ctx, e)
 	}
 
 	// send U to product
-	ctx.Loopback(msg.ProductId, &Message{
-		Stage:  Stage_PRODUCT,
-		Rating: msg,
-		F:      e.U,
-		Iters:  uint32(l.params.Iterations),
-	})
+	ctx.Loopback(msg.Rating.ProductId, msg)
 }
 
 //
@@ -79,57 +187,172 @@ func (l *Learner) stages(refeed goka.Stream) goka.ProcessCallback {
 	return func(ctx goka.Context, m interface{}) {
 		msg := m.(*Message)
 		e := getEntry(ctx)
+		l.metrics.message(msg.Stage)
+		if l.hot != nil {
+			l.scheduleFlushes(ctx)
+		}
 
 		switch msg.Stage {
 		case Stage_ENTRY: // send U to product
-			if e.U.Rank() != l.params.Rank {
-				e.U = NewFeatures(l.params.Rank).Randomize()
+			if l.initU(e) {
 				setEntry(ctx, e)
 			}
-			msg.Stage++
-			msg.F = e.U
+			l.reenter(e, msg, ctx.Timestamp())
 			ctx.Loopback(msg.Rating.ProductId, msg)
 
 		case Stage_PRODUCT: // validate, learn P and send P to user
-			if e.P.Rank() != l.params.Rank {
-				e.P = NewFeatures(l.params.Rank).Randomize()
+			if l.hot != nil && l.batchProduct(ctx, e, msg) {
+				return
 			}
-
-			// validate prediction before learning it
-			if msg.Iters == uint32(l.params.Iterations) {
-				// only validate in the first iteration
-				l.v.Validate(e.P.Predict(msg.F, l.sgd.Bias()), msg.Rating.Score)
-			}
-
-			// update P
-			l.sgd.Apply(e.P, msg.F, msg.Rating.Score)
+			learned := l.learnProduct(e, msg, ctx.Timestamp())
 			setEntry(ctx, e)
+			if learned {
+				ctx.Loopback(msg.Rating.UserId, msg)
+			}
 
-			// send P to user
-			msg.Stage++
-			msg.F = e.P
-			ctx.Loopback(msg.Rating.UserId, msg)
+		case Stage_FLUSH: // learn P of batched messages and send P to users
+			l.flushProduct(ctx, e, msg)
 
 		case Stage_USER: // learn U and send rating to refeeder
-			if e.U.Rank() != l.params.Rank {
-				e.U = NewFeatures(l.params.Rank).Randomize()
-			}
-
-			// update U
-			l.sgd.Apply(e.U, msg.F, msg.Rating.Score)
+			again := l.learnUser(e, msg)
 			setEntry(ctx, e)
-
-			// reiterate?
-			if msg.Iters > 1 {
-				msg.Iters--
-				msg.Stage = Stage_ENTRY
-				msg.F = nil
+			if again {
 				ctx.Emit(refeed, ctx.Key(), msg)
 			}
 		}
 	}
 }
 
+// The following methods implement the stages on entries independently of
+// goka, so that they are shared by the learner processor and LocalTrainer.
+
+// initU initializes random U features in the entry if their rank differs. It
+// returns whether the entry changed.
+func (l *Learner) initU(e *Entry) bool {
+	if e.U.Rank() == l.params.Rank {
+		return false
+	}
+	e.U = l.randomFeatures()
+	l.metrics.reinit("u")
+	return true
+}
+
+// initP initializes random P features in the entry if their rank differs. It
+// returns whether the entry changed.
+func (l *Learner) initP(e *Entry) bool {
+	if e.P.Rank() == l.params.Rank {
+		return false
+	}
+	e.P = l.randomFeatures()
+	l.metrics.reinit("p")
+	return true
+}
+
+// randomFeatures returns randomly initialized features.
+func (l *Learner) randomFeatures() *Features {
+	f := NewFeatures(l.params.Rank)
+	if l.rnd == nil {
+		return f.Randomize()
+	}
+	l.rndMutex.Lock()
+	for i := range f.V {
+		f.V[i] = l.rnd.Float64()
+	}
+	l.rndMutex.Unlock()
+	return f
+}
+
+// enter starts learning a rating in the user entry e at time ts. It returns
+// the message sending U to the product and whether the entry changed.
+func (l *Learner) enter(e *Entry, r *Rating, ts time.Time) (*Message, bool) {
+	l.metrics.rating()
+	changed := l.initU(e)
+	if (l.ratedMax > 0 || l.ratedAge > 0) && !l.isHoldout(r) {
+		e.addRated(r.ProductId, ts, l.ratedMax, l.ratedAge)
+		changed = true
+	}
+	return &Message{
+		Stage:     Stage_PRODUCT,
+		Rating:    r,
+		F:         e.U,
+		Iters:     uint32(l.params.Iterations),
+		Timestamp: ts.UnixNano() / int64(time.Millisecond),
+		Entered:   ts.UnixNano() / int64(time.Millisecond),
+	}, changed
+}
+
+// reenter prepares a refed message to send U of the user entry e to the
+// product again.
+func (l *Learner) reenter(e *Entry, msg *Message, ts time.Time) {
+	msg.Stage = Stage_PRODUCT
+	msg.F = e.U
+	msg.Timestamp = ts.UnixNano() / int64(time.Millisecond)
+}
+
+// learnProduct validates the prediction of the rating and learns P of the
+// product entry e with the U features in msg. It prepares msg to send P to
+// the user and returns true, unless the rating is held out.
+func (l *Learner) learnProduct(e *Entry, msg *Message, ts time.Time) bool {
+	l.initP(e)
+
+	// evaluate held-out ratings without learning them
+	if l.isHoldout(msg.Rating) {
+		l.validate(l.holdoutV, e, msg, ts)
+		return false
+	}
+
+	// validate prediction before learning it
+	l.validate(l.v, e, msg, ts)
+
+	// update P
+	l.sgd.Apply(e.P, msg.F, msg.Rating.Score)
+
+	// send P to user
+	msg.Stage = Stage_USER
+	msg.F = e.P
+	return true
+}
+
+// validate validates the prediction of the rating in msg with P of the
+// product entry e, if v is set.
+func (l *Learner) validate(v ContextValidator, e *Entry, msg *Message, ts time.Time) {
+	if v != nil {
+		validatePrediction(v, msg, e.P.Predict(msg.F, l.sgd.Bias()), ts)
+	}
+}
+
+// validatePrediction validates the prediction of the rating in msg.
+func validatePrediction(v ContextValidator, msg *Message, prediction float64, ts time.Time) {
+	v.ValidateContext(&Validation{
+		Prediction: prediction,
+		Rating:     msg.Rating,
+		Iters:      msg.Iters,
+		Timestamp:  ts,
+	})
+}
+
+// learnUser learns U of the user entry e with the P features in msg. If the
+// rating has to be iterated again, it prepares msg for the ENTRY stage and
+// returns true.
+func (l *Learner) learnUser(e *Entry, msg *Message) bool {
+	l.initU(e)
+
+	// update U
+	l.sgd.Apply(e.U, msg.F, msg.Rating.Score)
+	if msg.Entered > 0 {
+		l.metrics.update(time.Since(time.Unix(0, msg.Entered*int64(time.Millisecond))))
+	}
+
+	// reiterate?
+	if msg.Iters <= 1 {
+		return false
+	}
+	msg.Iters--
+	msg.Stage = Stage_ENTRY
+	msg.F = nil
+	return true
+}
+
 // update updates feature vectors of the model.
 func (l *Learner) update(ctx goka.Context, m interface{}) {
 	msg := m.(*Update)
@@ -0,0 +1,274 @@
+package cofire
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// stripes is the number of locks of the user and product entries when
+// training with several workers.
+const stripes = 1024
+
+// LocalTrainer trains a model in memory with the same stages, iterations and
+// validation as the learner processor, but without Kafka. Each iteration of
+// the ratings corresponds to a refeed of all ratings: a rating is learned
+// again only after all other ratings of the previous iteration. With
+// WithWorkers, the ratings of an iteration are learned in parallel.
+type LocalTrainer struct {
+	l       *Learner
+	model   MemoryModel
+	now     func() time.Time
+	stripes [stripes]sync.Mutex
+
+	// goroutines training the ratings and their schedule
+	workers       int
+	deterministic bool
+}
+
+// LocalTrainerOption configures a LocalTrainer. Every LearnerOption is a
+// LocalTrainerOption.
+type LocalTrainerOption interface {
+	applyLocal(t *LocalTrainer)
+}
+
+func (o LearnerOption) applyLocal(t *LocalTrainer) { o(t.l) }
+
+// localTrainerOption is an option that only applies to LocalTrainer.
+type localTrainerOption func(*LocalTrainer)
+
+func (o localTrainerOption) applyLocal(t *LocalTrainer) { o(t) }
+
+// WithWorkers makes a LocalTrainer train with n goroutines. Workers update
+// the features of different ratings concurrently, locking only the user and
+// product entries of each rating. If deterministic is set, workers instead
+// train blocks of ratings with disjoint users and products in a fixed
+// schedule, so that the model only depends on the seed and n.
+func WithWorkers(n int, deterministic bool) LocalTrainerOption {
+	return localTrainerOption(func(t *LocalTrainer) {
+		t.workers = n
+		t.deterministic = deterministic
+	})
+}
+
+// NewLocalTrainer creates a LocalTrainer. The validator and options are the
+// ones of NewLearner, where options concerning topics have no effect, plus
+// options of LocalTrainer such as WithWorkers.
+func NewLocalTrainer(validator Validator, params Parameters, opts ...LocalTrainerOption) *LocalTrainer {
+	t := &LocalTrainer{
+		l:     newLearner("local", validator, params),
+		model: make(MemoryModel),
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt.applyLocal(t)
+	}
+	return t
+}
+
+// Train learns the ratings in params.Iterations iterations. Ratings are
+// timestamped with their Timestamp if set, otherwise with the current time.
+// With WithWorkers, validators have to be safe for concurrent use.
+func (t *LocalTrainer) Train(ratings []Rating) {
+	jobs := t.jobs(ratings)
+	for first := true; len(jobs) > 0; first = false {
+		switch {
+		case t.workers <= 1:
+			jobs = t.epoch(t.l, jobs, first)
+		case t.deterministic:
+			jobs = t.epochBlocks(jobs, first)
+		default:
+			jobs = t.epochStriped(jobs, first)
+		}
+	}
+}
+
+// job is a rating with its message and entries.
+type job struct {
+	r             *Rating
+	msg           *Message
+	user, product *Entry
+	uh, ph        uint32 // hashes of the user and product keys
+	again         bool
+}
+
+// jobs creates the jobs of the ratings, initializing the features in the
+// order the ratings would initialize them.
+func (t *LocalTrainer) jobs(ratings []Rating) []*job {
+	jobs := make([]*job, len(ratings))
+	for i := range ratings {
+		r := &ratings[i]
+		j := &job{
+			r:       r,
+			user:    t.entry(r.UserId),
+			product: t.entry(r.ProductId),
+			uh:      hash(r.UserId),
+			ph:      hash(r.ProductId),
+		}
+		t.l.initU(j.user)
+		t.l.initP(j.product)
+		jobs[i] = j
+	}
+	return jobs
+}
+
+// learn runs an iteration of the job with l. It sets j.again if the rating
+// has to be iterated again.
+func (t *LocalTrainer) learn(l *Learner, j *job, first bool) {
+	ts := t.timestamp(j.r)
+	if first {
+		j.msg, _ = l.enter(j.user, j.r, ts)
+	} else {
+		l.reenter(j.user, j.msg, ts)
+	}
+
+	// features are rounded to the precision of the table and loop messages
+	// after every update, like encoding them would
+	j.user.U.quantize(l.precision)
+	if !l.learnProduct(j.product, j.msg, ts) {
+		j.again = false
+		return
+	}
+	j.product.P.quantize(l.precision)
+	j.again = l.learnUser(j.user, j.msg)
+	j.user.U.quantize(l.precision)
+}
+
+// epoch runs an iteration of the jobs with l and returns the jobs to iterate
+// again.
+func (t *LocalTrainer) epoch(l *Learner, jobs []*job, first bool) []*job {
+	for _, j := range jobs {
+		t.learn(l, j, first)
+	}
+	return again(jobs)
+}
+
+// epochStriped runs an iteration of the jobs with workers training
+// contiguous chunks of the jobs. A worker locks the stripes of the user and
+// product of each job.
+func (t *LocalTrainer) epochStriped(jobs []*job, first bool) []*job {
+	var (
+		wg sync.WaitGroup
+		n  = t.workers
+	)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func(chunk []*job) {
+			defer wg.Done()
+			for _, j := range chunk {
+				us, ps := j.uh%stripes, j.ph%stripes
+				if us > ps {
+					us, ps = ps, us
+				}
+				t.stripes[us].Lock()
+				if ps != us {
+					t.stripes[ps].Lock()
+				}
+				t.learn(t.l, j, first)
+				if ps != us {
+					t.stripes[ps].Unlock()
+				}
+				t.stripes[us].Unlock()
+			}
+		}(jobs[w*len(jobs)/n : (w+1)*len(jobs)/n])
+	}
+	wg.Wait()
+	return again(jobs)
+}
+
+// epochBlocks runs an iteration of the jobs in blocks of users and products.
+// In each of n rounds, worker w trains the block of user bucket w and product
+// bucket w+round, so that no two workers update the same features. Workers
+// add to the global bias in their own SGD, which is merged after each round
+// in worker order.
+func (t *LocalTrainer) epochBlocks(jobs []*job, first bool) []*job {
+	n := uint32(t.workers)
+	blocks := make([][]*job, n*n)
+	for _, j := range jobs {
+		b := j.uh%n*n + j.ph%n
+		blocks[b] = append(blocks[b], j)
+	}
+
+	sgds := make([]*SGD, n)
+	for round := uint32(0); round < n; round++ {
+		var wg sync.WaitGroup
+		sum, count := t.l.sgd.sum()
+		for w := uint32(0); w < n; w++ {
+			sgds[w] = NewSGD(t.l.sgd.Gamma, t.l.sgd.Lambda)
+			sgds[w].addSum(sum, count)
+			wg.Add(1)
+			go func(l *Learner, block []*job) {
+				defer wg.Done()
+				t.epoch(l, block, first)
+			}(t.l.fork(sgds[w]), blocks[w*n+(w+round)%n])
+		}
+		wg.Wait()
+		for _, s := range sgds {
+			ws, wc := s.sum()
+			t.l.sgd.addSum(ws-sum, wc-count)
+		}
+	}
+	return again(jobs)
+}
+
+// fork returns a learner sharing the configuration of l but learning with
+// sgd. Features are initialized before forking, so it has no random source.
+func (l *Learner) fork(sgd *SGD) *Learner {
+	return &Learner{
+		group:     l.group,
+		params:    l.params,
+		v:         l.v,
+		sgd:       sgd,
+		metrics:   l.metrics,
+		ratedMax:  l.ratedMax,
+		precision: l.precision,
+		ratedAge:  l.ratedAge,
+		holdout:   l.holdout,
+		holdoutV:  l.holdoutV,
+	}
+}
+
+// again returns the jobs to iterate again in their order.
+func again(jobs []*job) []*job {
+	var next []*job
+	for _, j := range jobs {
+		if j.again {
+			next = append(next, j)
+		}
+	}
+	return next
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// entry returns the entry of a key, creating it if missing.
+func (t *LocalTrainer) entry(key string) *Entry {
+	e := t.model[key]
+	if e == nil {
+		e = new(Entry)
+		t.model[key] = e
+	}
+	return e
+}
+
+func (t *LocalTrainer) timestamp(r *Rating) time.Time {
+	if r.Timestamp > 0 {
+		return time.Unix(0, r.Timestamp*int64(time.Millisecond))
+	}
+	return t.now()
+}
+
+// Model returns the trained model.
+func (t *LocalTrainer) Model() MemoryModel {
+	return t.model
+}
+
+// Bias returns the global bias of the trained model, ie, the average score
+// of all ratings learned.
+func (t *LocalTrainer) Bias() float64 {
+	return t.l.sgd.Bias()
+}
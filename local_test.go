@@ -0,0 +1,229 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+// streamContext runs the learner's stages on an in-memory table, processing
+// loopbacks depth first and queueing refed messages.
+type streamContext struct {
+	mockContext
+	key    string
+	table  MemoryModel
+	stages goka.ProcessCallback
+	refeed []*Message
+}
+
+func (c *streamContext) Key() string            { return c.key }
+func (c *streamContext) SetValue(v interface{}) { c.table[c.key] = v.(*Entry) }
+func (c *streamContext) Value() interface{} {
+	if e, ok := c.table[c.key]; ok {
+		return e
+	}
+	return nil
+}
+func (c *streamContext) Loopback(key string, m interface{}) { c.process(key, m.(*Message)) }
+func (c *streamContext) Emit(t goka.Stream, k string, m interface{}) {
+	c.refeed = append(c.refeed, m.(*Message))
+}
+
+func (c *streamContext) process(key string, msg *Message) {
+	prev := c.key
+	c.key = key
+	c.stages(c, msg)
+	c.key = prev
+}
+
+// iterValidator validates predictions per remaining iterations.
+type iterValidator map[uint32]*ErrorValidator
+
+func (s iterValidator) ValidateContext(v *Validation) {
+	if s[v.Iters] == nil {
+		s[v.Iters] = NewErrorValidator()
+	}
+	s[v.Iters].Validate(v.Prediction, v.Rating.Score)
+}
+
+func localRatings() []Rating {
+	var ratings []Rating
+	for u := 0; u < 10; u++ {
+		for p := 0; p < 10; p += u%3 + 1 {
+			ratings = append(ratings, Rating{
+				UserId:    fmt.Sprintf("user%d", u),
+				ProductId: fmt.Sprintf("prod%d", p),
+				Score:     float64((u + p) % 5),
+			})
+		}
+	}
+	return ratings
+}
+
+func TestLocalTrainer_Stream(t *testing.T) {
+	var (
+		params  = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 3}
+		ratings = localRatings()
+		ts      = time.Now()
+		local   = make(iterValidator)
+		stream  = make(iterValidator)
+	)
+
+	trainer := NewLocalTrainer(nil, params, WithContextValidator(local), WithSeed(1), WithRatedItems(5, 0))
+	trainer.now = func() time.Time { return ts }
+	trainer.Train(ratings)
+
+	l := newLearner("test", nil, params)
+	WithContextValidator(stream)(l)
+	WithSeed(1)(l)
+	WithRatedItems(5, 0)(l)
+	ctx := &streamContext{
+		mockContext: mockContext{ts: ts},
+		table:       make(MemoryModel),
+		stages:      l.stages("refeed"),
+	}
+	for i := range ratings {
+		ctx.key = ratings[i].UserId
+		l.entry(ctx, &ratings[i])
+	}
+	for len(ctx.refeed) > 0 {
+		msgs := ctx.refeed
+		ctx.refeed = nil
+		for _, msg := range msgs {
+			ctx.process(msg.Rating.UserId, msg)
+		}
+	}
+
+	if !reflect.DeepEqual(trainer.Model(), ctx.table) {
+		t.Errorf("local and streaming models differ")
+	}
+	if trainer.Bias() != l.sgd.Bias() {
+		t.Errorf("local bias %f differs from streaming bias %f", trainer.Bias(), l.sgd.Bias())
+	}
+	if !reflect.DeepEqual(local, stream) {
+		t.Errorf("local validations %v differ from streaming validations %v", local, stream)
+	}
+	if n := local[3].Count(); n != len(ratings) {
+		t.Errorf("expected %d validations of the first iteration, got %d", len(ratings), n)
+	}
+}
+
+func TestLocalTrainer_Seed(t *testing.T) {
+	var (
+		params  = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 2}
+		ratings = localRatings()
+		models  []MemoryModel
+	)
+	for i := 0; i < 2; i++ {
+		trainer := NewLocalTrainer(nil, params, WithSeed(7))
+		trainer.Train(ratings)
+		models = append(models, trainer.Model())
+	}
+	if !reflect.DeepEqual(models[0], models[1]) {
+		t.Errorf("models trained with the same seed differ")
+	}
+}
+
+func TestLocalTrainer_Holdout(t *testing.T) {
+	var (
+		params   = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 3}
+		ratings  = localRatings()
+		recorder = NewHoldoutRecorder(len(ratings))
+		v        = NewErrorValidator()
+	)
+	trainer := NewLocalTrainer(v, params, WithHoldout(0.3, recorder))
+	trainer.Train(ratings)
+
+	held := recorder.Ratings()
+	if len(held) == 0 {
+		t.Fatalf("no ratings held out")
+	}
+	if v.Count()+len(held) != len(ratings) {
+		t.Errorf("expected %d validations, got %d training and %d held out", len(ratings), v.Count(), len(held))
+	}
+	for _, r := range held {
+		if !Holdout(&r, 0.3) {
+			t.Errorf("rating %v recorded as held out", r)
+		}
+	}
+}
+
+func TestLocalTrainer_Workers(t *testing.T) {
+	var (
+		params  = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 3}
+		ratings = crossValRatings()
+	)
+	seq := NewLocalTrainer(nil, params, WithSeed(1))
+	seq.Train(ratings)
+
+	for _, deterministic := range []bool{false, true} {
+		var (
+			v       = NewErrorValidator()
+			trainer = NewLocalTrainer(v, params, WithSeed(1), WithWorkers(4, deterministic))
+		)
+		trainer.Train(ratings)
+		if v.Count() != len(ratings) {
+			t.Errorf("deterministic=%v: expected %d validations, got %d", deterministic, len(ratings), v.Count())
+		}
+		if len(trainer.Model()) != len(seq.Model()) {
+			t.Errorf("deterministic=%v: expected %d entries, got %d", deterministic, len(seq.Model()), len(trainer.Model()))
+		}
+		for key, e := range trainer.Model() {
+			if u := e.GetU(); u != nil && u.Updates != seq.Model()[key].U.Updates {
+				t.Errorf("deterministic=%v: %s has %d U updates, expected %d", deterministic, key, u.Updates, seq.Model()[key].U.Updates)
+			}
+		}
+		if math.Abs(trainer.Bias()-seq.Bias()) > 1e-9 {
+			t.Errorf("deterministic=%v: bias %f differs from %f", deterministic, trainer.Bias(), seq.Bias())
+		}
+	}
+}
+
+func TestLocalTrainer_Deterministic(t *testing.T) {
+	var (
+		params  = Parameters{Rank: 3, Gamma: 0.01, Lambda: 0.01, Iterations: 3}
+		ratings = crossValRatings()
+		models  []MemoryModel
+		biases  []float64
+	)
+	for i := 0; i < 3; i++ {
+		trainer := NewLocalTrainer(nil, params, WithSeed(7), WithWorkers(4, true))
+		trainer.Train(ratings)
+		models = append(models, trainer.Model())
+		biases = append(biases, trainer.Bias())
+	}
+	for i := 1; i < len(models); i++ {
+		if !reflect.DeepEqual(models[0], models[i]) || biases[0] != biases[i] {
+			t.Errorf("deterministic models differ")
+		}
+	}
+}
+
+func BenchmarkLocalTrainer(b *testing.B) {
+	var ratings []Rating
+	for u := 0; u < 1000; u++ {
+		for p := 0; p < 100; p++ {
+			ratings = append(ratings, Rating{
+				UserId:    fmt.Sprintf("user%d", u),
+				ProductId: fmt.Sprintf("prod%d", (u*7+p*13)%1000),
+				Score:     float64((u + p) % 5),
+			})
+		}
+	}
+	params := Parameters{Rank: 10, Gamma: 0.01, Lambda: 0.01, Iterations: 1}
+	for _, workers := range []int{1, 2, 4, 8} {
+		for _, deterministic := range []bool{false, true} {
+			b.Run(fmt.Sprintf("workers=%d/deterministic=%v", workers, deterministic), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					trainer := NewLocalTrainer(nil, params, WithSeed(1), WithWorkers(workers, deterministic))
+					trainer.Train(ratings)
+				}
+				b.ReportMetric(float64(b.N*len(ratings))/b.Elapsed().Seconds(), "ratings/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package cofire
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lovoo/goka"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "cofire"
+
+// ErrorReporter is a validator reporting its root mean square error, eg,
+// ErrorValidator. If it also has a MAE method, the mean absolute error is
+// reported as well.
+type ErrorReporter interface {
+	RMSE() float64
+}
+
+// Metrics are Prometheus metrics of the learner and refeeder processors of a
+// cofire group. Metrics is a prometheus.Collector, so it can be registered in
+// any registry, or it can be served with Handler.
+type Metrics struct {
+	ratings     prometheus.Counter
+	messages    *prometheus.CounterVec
+	reinits     *prometheus.CounterVec
+	refeeds     prometheus.Counter
+	refeedDelay prometheus.Histogram
+	latency     prometheus.Histogram
+	hotProducts prometheus.Gauge
+	hotBatches  prometheus.Histogram
+
+	biasDesc *prometheus.Desc
+	rmseDesc *prometheus.Desc
+	maeDesc  *prometheus.Desc
+
+	m          sync.RWMutex
+	bias       func() float64
+	validators map[string]ErrorReporter
+}
+
+// NewMetrics creates the metrics of a cofire group.
+func NewMetrics(group goka.Group) *Metrics {
+	labels := prometheus.Labels{"group": string(group)}
+	return &Metrics{
+		ratings: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "ratings_total",
+			Help:        "Number of ratings received by the learner.",
+			ConstLabels: labels,
+		}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "messages_total",
+			Help:        "Number of internal messages processed by the learner per stage.",
+			ConstLabels: labels,
+		}, []string{"stage"}),
+		reinits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "reinitializations_total",
+			Help:        "Number of U or P features randomly (re)initialized because their rank differed.",
+			ConstLabels: labels,
+		}, []string{"features"}),
+		refeeds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Name:        "refeeds_total",
+			Help:        "Number of messages emitted by the refeeder.",
+			ConstLabels: labels,
+		}),
+		refeedDelay: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "refeed_delay_seconds",
+			Help:        "Delay between a message arriving at the refeeder and its emission.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.1, 2, 16),
+		}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "update_latency_seconds",
+			Help:        "Latency between a rating entering the learner and the updates of U in the USER stage of its iterations.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.001, 2, 16),
+		}),
+		hotProducts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   metricsNamespace,
+			Name:        "hot_products",
+			Help:        "Number of hot products whose PRODUCT messages are batched.",
+			ConstLabels: labels,
+		}),
+		hotBatches: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metricsNamespace,
+			Name:        "hot_batch_size",
+			Help:        "Number of PRODUCT messages learned in one update of a hot product.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(1, 2, 12),
+		}),
+		biasDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "global_bias"),
+			"Global bias of the learner's SGD.",
+			nil, labels),
+		rmseDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "validator", "rmse"),
+			"Root mean square error of a validator.",
+			[]string{"validator"}, labels),
+		maeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "validator", "mae"),
+			"Mean absolute error of a validator.",
+			[]string{"validator"}, labels),
+		validators: make(map[string]ErrorReporter),
+	}
+}
+
+// WithMetrics makes the learner record its metrics in m. A nil m records no
+// metrics.
+func WithMetrics(m *Metrics) LearnerOption {
+	return func(l *Learner) {
+		l.metrics = m
+		if m == nil {
+			return
+		}
+		m.m.Lock()
+		m.bias = l.sgd.Bias
+		m.m.Unlock()
+	}
+}
+
+// AddValidator reports the errors of a validator under the given name.
+func (m *Metrics) AddValidator(name string, v ErrorReporter) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.validators[name] = v
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus
+// exposition format.
+func (m *Metrics) Handler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.ratings.Describe(ch)
+	m.messages.Describe(ch)
+	m.reinits.Describe(ch)
+	m.refeeds.Describe(ch)
+	m.refeedDelay.Describe(ch)
+	m.latency.Describe(ch)
+	m.hotProducts.Describe(ch)
+	m.hotBatches.Describe(ch)
+	ch <- m.biasDesc
+	ch <- m.rmseDesc
+	ch <- m.maeDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.ratings.Collect(ch)
+	m.messages.Collect(ch)
+	m.reinits.Collect(ch)
+	m.refeeds.Collect(ch)
+	m.refeedDelay.Collect(ch)
+	m.latency.Collect(ch)
+	m.hotProducts.Collect(ch)
+	m.hotBatches.Collect(ch)
+
+	m.m.RLock()
+	defer m.m.RUnlock()
+	if m.bias != nil {
+		ch <- prometheus.MustNewConstMetric(m.biasDesc, prometheus.GaugeValue, m.bias())
+	}
+	for name, v := range m.validators {
+		ch <- prometheus.MustNewConstMetric(m.rmseDesc, prometheus.GaugeValue, v.RMSE(), name)
+		if mae, ok := v.(interface{ MAE() float64 }); ok {
+			ch <- prometheus.MustNewConstMetric(m.maeDesc, prometheus.GaugeValue, mae.MAE(), name)
+		}
+	}
+}
+
+// The following methods record events of the processors. They do nothing if
+// called on nil metrics.
+
+func (m *Metrics) rating() {
+	if m != nil {
+		m.ratings.Inc()
+	}
+}
+
+func (m *Metrics) message(s Stage) {
+	if m != nil {
+		m.messages.WithLabelValues(s.String()).Inc()
+	}
+}
+
+func (m *Metrics) reinit(features string) {
+	if m != nil {
+		m.reinits.WithLabelValues(features).Inc()
+	}
+}
+
+func (m *Metrics) refeed(delay time.Duration) {
+	if m != nil {
+		m.refeeds.Inc()
+		m.refeedDelay.Observe(delay.Seconds())
+	}
+}
+
+func (m *Metrics) update(latency time.Duration) {
+	if m != nil {
+		m.latency.Observe(latency.Seconds())
+	}
+}
+
+func (m *Metrics) hot(delta float64) {
+	if m != nil {
+		m.hotProducts.Add(delta)
+	}
+}
+
+func (m *Metrics) hotBatch(size int) {
+	if m != nil {
+		m.hotBatches.Observe(float64(size))
+	}
+}
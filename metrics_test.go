@@ -0,0 +1,127 @@
+package cofire
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("error reading metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetrics_Learner(t *testing.T) {
+	params := DefaultParams()
+	params.Iterations = 2
+	m := NewMetrics("test")
+	v := NewErrorValidator()
+	m.AddValidator("error", v)
+
+	l := newLearner("test", v, params)
+	WithMetrics(m)(l)
+
+	ctx := &mockContext{ts: time.Now()}
+	l.entry(ctx, &Rating{UserId: "user", ProductId: "prod", Score: 1})
+
+	var emitted *Message
+	ctx.emitCheck = func(_ goka.Stream, _ string, m interface{}) {
+		emitted = m.(*Message)
+	}
+	stages := l.stages("refeed")
+	stages(ctx, &Message{Stage: Stage_PRODUCT, Rating: &Rating{Score: 1}, F: NewFeatures(params.Rank), Iters: 2})
+	stages(ctx, &Message{Stage: Stage_USER, Rating: &Rating{Score: 1}, F: NewFeatures(params.Rank), Iters: 2, Entered: 1})
+	if emitted == nil {
+		t.Fatalf("expected message to be refed")
+	}
+
+	body := scrape(t, m)
+	for _, s := range []string{
+		`cofire_ratings_total{group="test"} 1`,
+		`cofire_messages_total{group="test",stage="PRODUCT"} 1`,
+		`cofire_messages_total{group="test",stage="USER"} 1`,
+		`cofire_reinitializations_total{features="u",group="test"} 1`,
+		`cofire_reinitializations_total{features="p",group="test"} 1`,
+		`cofire_update_latency_seconds_count{group="test"} 1`,
+		`cofire_global_bias{group="test"}`,
+		`cofire_validator_rmse{group="test",validator="error"} 1`,
+		`cofire_validator_mae{group="test",validator="error"} 1`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("missing %q in metrics:\n%s", s, body)
+		}
+	}
+}
+
+func TestMetrics_Latency(t *testing.T) {
+	params := DefaultParams()
+	params.Iterations = 2
+	m := NewMetrics("test")
+	l := newLearner("test", nil, params)
+	WithMetrics(m)(l)
+
+	// the latency of every iteration counts from the rating entering the
+	// learner, not from the reentering of the iteration
+	var (
+		u, p   Entry
+		msg, _ = l.enter(&u, &Rating{Score: 1}, time.Now().Add(-time.Hour))
+	)
+	for again := true; again; {
+		l.reenter(&u, msg, time.Now())
+		l.learnProduct(&p, msg, time.Now())
+		again = l.learnUser(&u, msg)
+	}
+
+	body := scrape(t, m)
+	for _, s := range []string{
+		`cofire_update_latency_seconds_bucket{group="test",le="32.768"} 0`,
+		`cofire_update_latency_seconds_count{group="test"} 2`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("missing %q in metrics:\n%s", s, body)
+		}
+	}
+}
+
+func TestMetrics_Refeeder(t *testing.T) {
+	m := NewMetrics("test")
+	cb := refeed("topic", 0, waiter, m)
+
+	ctx := &mockContext{ts: time.Now().Add(-time.Second)}
+	ctx.emitCheck = func(goka.Stream, string, interface{}) {}
+	cb(ctx, "some message")
+
+	body := scrape(t, m)
+	for _, s := range []string{
+		`cofire_refeeds_total{group="test"} 1`,
+		`cofire_refeed_delay_seconds_count{group="test"} 1`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("missing %q in metrics:\n%s", s, body)
+		}
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+	m.rating()
+	m.message(Stage_ENTRY)
+	m.reinit("u")
+	m.refeed(time.Second)
+	m.update(time.Second)
+
+	l := newLearner("test", nil, DefaultParams())
+	WithMetrics(m)(l)
+	if l.metrics != nil {
+		t.Errorf("expected no metrics, got %v", l.metrics)
+	}
+}
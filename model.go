@@ -0,0 +1,72 @@
+package cofire
+
+import (
+	"github.com/lovoo/goka"
+)
+
+// Model gives read access to the learnt entries of users and products.
+type Model interface {
+	// Get returns the entry of a key or nil if the key is unknown.
+	Get(key string) (*Entry, error)
+	// Range calls fn for each key and entry in the model until fn returns
+	// false.
+	Range(fn func(key string, e *Entry) bool) error
+}
+
+// NewViewModel returns a Model backed by a view of the learner's group table.
+func NewViewModel(view *goka.View) Model {
+	return &viewModel{view}
+}
+
+type viewModel struct {
+	view *goka.View
+}
+
+func (m *viewModel) Get(key string) (*Entry, error) {
+	v, err := m.view.Get(key)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return v.(*Entry), nil
+}
+
+func (m *viewModel) Range(fn func(key string, e *Entry) bool) error {
+	it, err := m.view.Iterator()
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		v, err := it.Value()
+		if err != nil {
+			return err
+		}
+		e, ok := v.(*Entry)
+		if !ok {
+			continue
+		}
+		if !fn(it.Key(), e) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// MemoryModel is a Model kept in memory, eg, for tests or local training.
+type MemoryModel map[string]*Entry
+
+// Get returns the entry of a key or nil if the key is unknown.
+func (m MemoryModel) Get(key string) (*Entry, error) {
+	return m[key], nil
+}
+
+// Range calls fn for each key and entry in the model until fn returns false.
+func (m MemoryModel) Range(fn func(key string, e *Entry) bool) error {
+	for k, e := range m {
+		if !fn(k, e) {
+			return nil
+		}
+	}
+	return nil
+}
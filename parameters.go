@@ -1,15 +1,16 @@
 package cofire
 
-// Parameters configure the SGD algorithm.
+// Parameters configure the SGD algorithm. They can be stored as JSON, eg, as
+// written by the cofire tune command.
 type Parameters struct {
 	// Rank is the number of latent factors (features).
-	Rank int
+	Rank int `json:"rank"`
 	// Gamma is the learning step.
-	Gamma float64
+	Gamma float64 `json:"gamma"`
 	// Lambda is the regularization parameter.
-	Lambda float64
+	Lambda float64 `json:"lambda"`
 	// Iterations is the number of times the data will be used for training.
-	Iterations int
+	Iterations int `json:"iterations"`
 }
 
 // DefaultParams return the default parameters of SGD.
@@ -0,0 +1,196 @@
+package cofire
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+// popularityBuckets is the number of time slots in which the ratings of the
+// recent window are counted.
+const popularityBuckets = 24
+
+// NewPopularity returns the GroupGraph for a processor that maintains the
+// rating statistics of products, eg, to recommend popular products to users
+// that have not been learnt yet. The processor consumes the learner's input
+// and stores the statistics in the table of the "<group>-popularity" group.
+// Ratings of the last window are additionally counted in time slots, so that
+// products can be ranked by recent popularity.
+func NewPopularity(cofireGroup goka.Group, window time.Duration, opts ...PopularityOption) *goka.GroupGraph {
+	var (
+		group = fmt.Sprintf("%s-popularity", cofireGroup)
+		input = fmt.Sprintf("%s-input", cofireGroup)
+		o     = popularityOptions{inputCodec: new(RatingCodec)}
+	)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return goka.DefineGroup(goka.Group(group),
+		// ratings are keyed by user, so forward them to the product
+		goka.Input(goka.Stream(input), o.inputCodec, func(ctx goka.Context, m interface{}) {
+			ctx.Loopback(m.(*Rating).ProductId, m)
+		}),
+		goka.Loop(new(RatingCodec), func(ctx goka.Context, m interface{}) {
+			p, ok := ctx.Value().(*Popularity)
+			if !ok {
+				p = new(Popularity)
+			}
+			p.add(m.(*Rating).Score, ctx.Timestamp(), window)
+			ctx.SetValue(p)
+		}),
+		goka.Persist(new(PopularityCodec)),
+	)
+}
+
+// PopularityOption configures the popularity processor.
+type PopularityOption func(*popularityOptions)
+
+type popularityOptions struct {
+	inputCodec goka.Codec
+}
+
+// WithPopularityInputCodec sets the codec of the ratings in the
+// "<group>-input" topic, which has to match the learner's input codec. The
+// default is RatingCodec.
+func WithPopularityInputCodec(c goka.Codec) PopularityOption {
+	return func(o *popularityOptions) {
+		o.inputCodec = c
+	}
+}
+
+// add adds a score to the statistics and drops time slots older than window.
+func (p *Popularity) add(score float64, ts time.Time, window time.Duration) {
+	p.Count++
+	p.Sum += score
+	if window <= 0 {
+		return
+	}
+
+	var (
+		start   = ts.Truncate(window/popularityBuckets).UnixNano() / int64(time.Millisecond)
+		expired = ts.Add(-window).UnixNano() / int64(time.Millisecond)
+		buckets = p.Buckets[:0]
+		bucket  *Bucket
+	)
+	for _, b := range p.Buckets {
+		if b.Start < expired {
+			continue
+		}
+		if b.Start == start {
+			bucket = b
+		}
+		buckets = append(buckets, b)
+	}
+	if bucket == nil {
+		bucket = &Bucket{Start: start}
+		buckets = append(buckets, bucket)
+	}
+	bucket.Count++
+	bucket.Sum += score
+	p.Buckets = buckets
+}
+
+// Average returns the average score of the product.
+func (p *Popularity) Average() float64 {
+	if p.GetCount() == 0 {
+		return 0
+	}
+	return p.Sum / float64(p.Count)
+}
+
+// Recent returns the number of ratings in the time window until now.
+func (p *Popularity) Recent(now time.Time, window time.Duration) uint64 {
+	var (
+		since = now.Add(-window).UnixNano() / int64(time.Millisecond)
+		count uint64
+	)
+	for _, b := range p.GetBuckets() {
+		if b.Start >= since {
+			count += b.Count
+		}
+	}
+	return count
+}
+
+// PopularityModel gives read access to the rating statistics of products.
+type PopularityModel interface {
+	// Range calls fn for each product and its statistics until fn returns
+	// false.
+	Range(fn func(product string, p *Popularity) bool) error
+}
+
+// NewPopularityViewModel returns a PopularityModel backed by a view of the
+// popularity table, ie, goka.GroupTable("<group>-popularity").
+func NewPopularityViewModel(view *goka.View) PopularityModel {
+	return &popularityViewModel{view}
+}
+
+type popularityViewModel struct {
+	view *goka.View
+}
+
+func (m *popularityViewModel) Range(fn func(product string, p *Popularity) bool) error {
+	it, err := m.view.Iterator()
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	for it.Next() {
+		v, err := it.Value()
+		if err != nil {
+			return err
+		}
+		p, ok := v.(*Popularity)
+		if !ok {
+			continue
+		}
+		if !fn(it.Key(), p) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// MemoryPopularityModel is a PopularityModel kept in memory.
+type MemoryPopularityModel map[string]*Popularity
+
+// Range calls fn for each product and its statistics until fn returns false.
+func (m MemoryPopularityModel) Range(fn func(product string, p *Popularity) bool) error {
+	for k, p := range m {
+		if !fn(k, p) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// TopPopular returns the k products with the most ratings, sorted by
+// descending number of ratings. If window is positive, only ratings in the
+// time window until now are counted. Products for which exclude returns true
+// are skipped; exclude may be nil.
+func TopPopular(m PopularityModel, now time.Time, window time.Duration, k int, exclude func(product string) bool) ([]*Recommendation, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+
+	top := newTopRecommendations(k)
+	err := m.Range(func(product string, p *Popularity) bool {
+		if exclude != nil && exclude(product) {
+			return true
+		}
+		count := p.Count
+		if window > 0 {
+			count = p.Recent(now, window)
+		}
+		if count > 0 {
+			top.offer(product, float64(count))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top.sorted(), nil
+}
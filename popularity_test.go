@@ -0,0 +1,86 @@
+package cofire
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPopularity(t *testing.T) {
+	var (
+		p      = new(Popularity)
+		window = 24 * time.Hour
+		start  = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	p.add(1, start, window)
+	p.add(2, start.Add(time.Minute), window)
+	p.add(3, start.Add(2*time.Hour), window)
+	if p.Count != 3 || p.Average() != 2 {
+		t.Errorf("unexpected statistics: %v", p)
+	}
+	if len(p.Buckets) != 2 {
+		t.Errorf("expected 2 buckets, got %d", len(p.Buckets))
+	}
+	if n := p.Recent(start.Add(3*time.Hour), window); n != 3 {
+		t.Errorf("recent: %d, expected: 3", n)
+	}
+	if n := p.Recent(start.Add(25*time.Hour), window); n != 1 {
+		t.Errorf("recent: %d, expected: 1", n)
+	}
+
+	// adding after the window drops the expired buckets
+	p.add(4, start.Add(30*time.Hour), window)
+	if len(p.Buckets) != 1 || p.Count != 4 {
+		t.Errorf("unexpected statistics: %v", p)
+	}
+}
+
+func TestPredictor_RecommendPopular(t *testing.T) {
+	var (
+		window = time.Hour
+		now    = time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+		pop    = make(MemoryPopularityModel)
+		ctx    = context.Background()
+	)
+	add := func(product string, ts time.Time) {
+		if pop[product] == nil {
+			pop[product] = new(Popularity)
+		}
+		pop[product].add(1, ts, window)
+	}
+	add("old", now.Add(-2*time.Hour))
+	add("old", now.Add(-2*time.Hour))
+	add("new", now.Add(-time.Minute))
+
+	p := NewPredictor(testModel(), WithPopularity(pop, window))
+	p.now = func() time.Time { return now }
+
+	// users with features get personalized recommendations
+	resp, err := p.Recommend(ctx, &RecommendRequest{UserId: "user", K: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Source != Source_PERSONALIZED {
+		t.Errorf("unexpected source: %v", resp.Source)
+	}
+
+	// unknown users get recently popular products
+	resp, err = p.Recommend(ctx, &RecommendRequest{UserId: "unknown", K: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Source != Source_POPULAR_RECENT || resp.Recommendations[0].ProductId != "new" {
+		t.Errorf("unexpected response: %v", resp)
+	}
+
+	// without recent ratings, popular products overall are returned
+	p.now = func() time.Time { return now.Add(time.Hour) }
+	resp, err = p.Recommend(ctx, &RecommendRequest{UserId: "unknown", K: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Source != Source_POPULAR || resp.Recommendations[0].ProductId != "old" {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
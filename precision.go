@@ -0,0 +1,153 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+)
+
+// Precision is the precision of the factors of encoded features. Codecs
+// decode features of any precision, so that the precision of a table or topic
+// can be changed without migrating the encoded features first.
+type Precision int
+
+const (
+	// Float64 stores the factors as doubles in Features.V.
+	Float64 Precision = iota
+	// Float32 stores the factors as floats in Features.V32, halving their
+	// size.
+	Float32
+	// Int8 quantizes the factors to a byte each in Features.Q with a scale
+	// factor, ie, to 1/8 of their size, using 255 steps between -max|v| and
+	// max|v|. Factors are rounded stochastically, so that updates smaller
+	// than a step are kept on average.
+	Int8
+)
+
+func (p Precision) String() string {
+	switch p {
+	case Float64:
+		return "float64"
+	case Float32:
+		return "float32"
+	case Int8:
+		return "int8"
+	}
+	return fmt.Sprintf("Precision(%d)", int(p))
+}
+
+// PrecisionOf returns the precision of a name as returned by String.
+func PrecisionOf(name string) (Precision, error) {
+	for _, p := range []Precision{Float64, Float32, Int8} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown precision %q", name)
+}
+
+// pack returns a copy of the features with the factors stored in precision
+// p. It returns f itself for Float64.
+func (f *Features) pack(p Precision) *Features {
+	if f == nil || p == Float64 {
+		return f
+	}
+	o := &Features{Bias: f.Bias, Updates: f.Updates}
+	switch p {
+	case Float32:
+		o.V32 = make([]float32, len(f.V))
+		for i, v := range f.V {
+			o.V32[i] = float32(v)
+		}
+	case Int8:
+		var max float64
+		for _, v := range f.V {
+			max = math.Max(max, math.Abs(v))
+		}
+		o.Q = make([]byte, len(f.V))
+		if max > 0 {
+			o.Scale = max / math.MaxInt8
+			for i, v := range f.V {
+				x := v / o.Scale
+				if r := math.Round(x); math.Abs(x-r) < 1e-9 {
+					// keep quantized factors, whose quotient may be off by an ulp
+					x = r
+				}
+				o.Q[i] = byte(int8(math.Floor(x + dither(f.Updates, i))))
+			}
+		}
+	}
+	return o
+}
+
+// dither returns a pseudo-random number in [0, 1) for the i-th factor of
+// features updated n times, so that rounding is stochastic but reproducible.
+func dither(n uint64, i int) float64 {
+	x := n*0x9e3779b97f4a7c15 + uint64(i)
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
+
+// unpack moves the factors stored in V32 or Q into V.
+func (f *Features) unpack() {
+	if f == nil || len(f.V) > 0 {
+		return
+	}
+	switch {
+	case len(f.V32) > 0:
+		f.V = make([]float64, len(f.V32))
+		for i, v := range f.V32 {
+			f.V[i] = float64(v)
+		}
+	case len(f.Q) > 0:
+		f.V = make([]float64, len(f.Q))
+		for i, q := range f.Q {
+			f.V[i] = float64(int8(q)) * f.Scale
+		}
+	}
+	f.V32, f.Q, f.Scale = nil, nil, 0
+}
+
+// quantize rounds the factors in place to precision p, as encoding and
+// decoding them does.
+func (f *Features) quantize(p Precision) {
+	if f == nil || p == Float64 {
+		return
+	}
+	q := f.pack(p)
+	q.unpack()
+	copy(f.V, q.V)
+}
+
+// packEntry returns a copy of the entry with features in precision p.
+func packEntry(e *Entry, p Precision) *Entry {
+	if e == nil || p == Float64 {
+		return e
+	}
+	return &Entry{U: e.U.pack(p), P: e.P.pack(p), Rated: e.Rated, ProductRate: e.ProductRate}
+}
+
+func (e *Entry) unpack() {
+	e.U.unpack()
+	e.P.unpack()
+}
+
+func packUpdate(u *Update, p Precision) *Update {
+	if u == nil || p == Float64 {
+		return u
+	}
+	return &Update{U: u.U.pack(p), P: u.P.pack(p)}
+}
+
+func (u *Update) unpack() {
+	u.U.unpack()
+	u.P.unpack()
+}
+
+func packMessage(m *Message, p Precision) *Message {
+	if m == nil || p == Float64 || m.F == nil {
+		return m
+	}
+	return &Message{Stage: m.Stage, Rating: m.Rating, F: m.F.pack(p), Iters: m.Iters, Timestamp: m.Timestamp, Entered: m.Entered}
+}
@@ -0,0 +1,137 @@
+package cofire
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/lovoo/goka"
+)
+
+func TestPrecision_Codecs(t *testing.T) {
+	var (
+		f = NewFeatures(128).Randomize()
+		e = &Entry{U: f, P: f.clone(), Rated: []*Rated{{ProductId: "prod"}}}
+	)
+	f.Bias, f.Updates = 0.5, 3
+	e.P.V[0] = -2
+
+	sizes := make(map[Precision]int)
+	for _, p := range []Precision{Float64, Float32, Int8} {
+		b, err := (&EntryCodec{Precision: p}).Encode(e)
+		if err != nil {
+			t.Fatalf("%s: error encoding: %v", p, err)
+		}
+		sizes[p] = len(b)
+		if len(e.U.V) != 128 || e.U.V32 != nil || e.U.Q != nil {
+			t.Fatalf("%s: encoding modified the entry", p)
+		}
+
+		// any codec decodes any precision
+		v, err := (&EntryCodec{Precision: (p + 1) % 3}).Decode(b)
+		if err != nil {
+			t.Fatalf("%s: error decoding: %v", p, err)
+		}
+		d := v.(*Entry)
+		if d.U.V32 != nil || d.U.Q != nil || d.U.Scale != 0 {
+			t.Errorf("%s: packed factors not cleared", p)
+		}
+		if d.U.Bias != 0.5 || d.U.Updates != 3 || !reflect.DeepEqual(d.Rated, e.Rated) {
+			t.Errorf("%s: unexpected entry %v", p, d)
+		}
+		tolerance := map[Precision]float64{Float64: 0, Float32: 1e-7, Int8: 2.0 / 127}[p]
+		for i, x := range e.P.V {
+			if math.Abs(d.P.V[i]-x) > tolerance {
+				t.Errorf("%s: factor %d is %f, expected %f", p, i, d.P.V[i], x)
+			}
+		}
+	}
+	if sizes[Float32] > sizes[Float64]*6/10 || sizes[Int8] > sizes[Float64]*2/10 {
+		t.Errorf("unexpected sizes: %v", sizes)
+	}
+}
+
+func TestPrecision_Messages(t *testing.T) {
+	var (
+		c = &messageCodec{Float32}
+		m = &Message{Stage: Stage_USER, Rating: &Rating{UserId: "user"}, F: &Features{V: []float64{0.5, 0.25}}, Iters: 2}
+	)
+	b, err := c.Encode(m)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	v, err := new(messageCodec).Decode(b)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(v, m) {
+		t.Errorf("expected %v, got %v", m, v)
+	}
+
+	// update codecs
+	u := &Update{P: &Features{V: []float64{0.5, -1}}}
+	b, err = (&UpdateCodec{Precision: Int8}).Encode(u)
+	if err != nil {
+		t.Fatalf("error encoding: %v", err)
+	}
+	v, err = new(UpdateCodec).Decode(b)
+	if err != nil {
+		t.Fatalf("error decoding: %v", err)
+	}
+	if p := v.(*Update).P.V; math.Abs(p[0]-0.5) > 1.0/127 || p[1] != -1 {
+		t.Errorf("unexpected features %v", p)
+	}
+}
+
+func TestPrecision_Quantize(t *testing.T) {
+	f := NewFeatures(10).Randomize()
+	for _, p := range []Precision{Float32, Int8} {
+		q := f.clone()
+		q.quantize(p)
+		again := q.clone()
+		again.quantize(p)
+		if !reflect.DeepEqual(q, again) {
+			t.Errorf("%s: quantizing is not idempotent", p)
+		}
+	}
+
+	// stochastic rounding keeps small updates on average
+	var sum float64
+	for n := uint64(0); n < 1000; n++ {
+		g := &Features{V: []float64{1, 0.001}, Updates: n}
+		g.quantize(Int8)
+		sum += g.V[1]
+	}
+	if mean := sum / 1000; math.Abs(mean-0.001) > 0.0005 {
+		t.Errorf("mean of rounded 0.001 is %f", mean)
+	}
+
+	if p, err := PrecisionOf("int8"); err != nil || p != Int8 {
+		t.Errorf("unexpected precision %s: %v", p, err)
+	}
+	if _, err := PrecisionOf("float16"); err == nil {
+		t.Errorf("expected error for unknown precision")
+	}
+}
+
+func TestPrecision_Options(t *testing.T) {
+	for _, tc := range []struct {
+		opts  []LearnerOption
+		codec goka.Codec
+	}{
+		{[]LearnerOption{WithPrecision(Int8)}, &EntryCodec{Precision: Int8}},
+		{[]LearnerOption{WithPrecision(Int8), WithTableCodec(new(EntryCodec))}, &EntryCodec{Precision: Int8}},
+		{[]LearnerOption{WithTableCodec(new(EntryCodec)), WithPrecision(Int8)}, &EntryCodec{Precision: Int8}},
+		{[]LearnerOption{WithTableCodec(&EntryCodec{Precision: Float32})}, &EntryCodec{Precision: Float32}},
+		{[]LearnerOption{WithPrecision(Int8), WithTableCodec(new(JSONEntryCodec))}, new(JSONEntryCodec)},
+		{[]LearnerOption{WithTableCodec(new(JSONEntryCodec)), WithPrecision(Int8)}, new(JSONEntryCodec)},
+	} {
+		l := newLearner("test", nil, DefaultParams())
+		for _, opt := range tc.opts {
+			opt(l)
+		}
+		if c := l.persistCodec(); !reflect.DeepEqual(c, tc.codec) {
+			t.Errorf("expected table codec %#v, got %#v", tc.codec, c)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package cofire
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultRecommendations = 10
+
+// Predictor implements the Predictor gRPC service on top of a model, typically
+// a view of the learner's group table (see NewViewModel).
+type Predictor struct {
+	model  Model
+	bias   float64
+	k      int
+	params Parameters
+
+	popularity PopularityModel
+	window     time.Duration
+	now        func() time.Time
+}
+
+// PredictorOption configures a Predictor.
+type PredictorOption func(*Predictor)
+
+// WithBias sets the global bias added to every prediction. By default the bias
+// is 0, which does not affect the order of recommendations.
+func WithBias(bias float64) PredictorOption {
+	return func(p *Predictor) {
+		p.bias = bias
+	}
+}
+
+// WithDefaultK sets the number of recommendations returned if a request does
+// not specify k.
+func WithDefaultK(k int) PredictorOption {
+	return func(p *Predictor) {
+		p.k = k
+	}
+}
+
+// WithParams sets the parameters used to fold in users from their ratings. By
+// default DefaultParams are used.
+func WithParams(params Parameters) PredictorOption {
+	return func(p *Predictor) {
+		p.params = params
+	}
+}
+
+// WithPopularity enables recommendations for users without U features. If a
+// user cannot be predicted, Recommend falls back to the products most rated in
+// the last window and then to the products most rated overall. The model is
+// typically a view of the table maintained by the NewPopularity processor.
+func WithPopularity(m PopularityModel, window time.Duration) PredictorOption {
+	return func(p *Predictor) {
+		p.popularity = m
+		p.window = window
+	}
+}
+
+// NewPredictor creates a Predictor serving predictions from model.
+func NewPredictor(model Model, opts ...PredictorOption) *Predictor {
+	p := &Predictor{
+		model:  model,
+		k:      defaultRecommendations,
+		params: DefaultParams(),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Predict predicts the score of a user for a product.
+func (p *Predictor) Predict(ctx context.Context, req *PredictRequest) (*PredictResponse, error) {
+	u, err := p.user(ctx, req.UserId, req.Ratings)
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.features(req.ProductId, (*Entry).GetP)
+	if err != nil {
+		return nil, err
+	}
+	return &PredictResponse{Score: u.Predict(f, p.bias)}, nil
+}
+
+// Recommend returns the products with the highest predicted scores for a user.
+// If the user cannot be predicted and popularity statistics are configured,
+// the most popular products are returned instead. Products rated by the user,
+// either kept in the user's entry (see WithRatedItems) or passed in the
+// request, are excluded unless the request includes them.
+func (p *Predictor) Recommend(ctx context.Context, req *RecommendRequest) (*RecommendResponse, error) {
+	k := int(req.K)
+	if k == 0 {
+		k = p.k
+	}
+
+	var exclude func(string) bool
+	if !req.IncludeRated {
+		e, err := p.model.Get(req.UserId)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		rated := ratedSet(e, req.Ratings)
+		exclude = func(product string) bool { return rated[product] }
+	}
+
+	u, err := p.user(ctx, req.UserId, req.Ratings)
+	if status.Code(err) == codes.NotFound && p.popularity != nil {
+		return p.recommendPopular(k, exclude)
+	} else if err != nil {
+		return nil, err
+	}
+
+	recs, err := TopK(p.model, u, p.bias, k, exclude)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(recs) == 0 && p.popularity != nil {
+		return p.recommendPopular(k, exclude)
+	}
+	return &RecommendResponse{Recommendations: recs, Source: Source_PERSONALIZED}, nil
+}
+
+// recommendPopular returns the products most rated in the recent window or, if
+// there are none, the products most rated overall.
+func (p *Predictor) recommendPopular(k int, exclude func(string) bool) (*RecommendResponse, error) {
+	if p.window > 0 {
+		recs, err := TopPopular(p.popularity, p.now(), p.window, k, exclude)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if len(recs) > 0 {
+			return &RecommendResponse{Recommendations: recs, Source: Source_POPULAR_RECENT}, nil
+		}
+	}
+	recs, err := TopPopular(p.popularity, p.now(), 0, k, exclude)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &RecommendResponse{Recommendations: recs, Source: Source_POPULAR}, nil
+}
+
+// GetEntry returns the learnt entry of a user or product.
+func (p *Predictor) GetEntry(ctx context.Context, req *GetEntryRequest) (*Entry, error) {
+	e, err := p.model.Get(req.Key)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if e == nil {
+		return nil, status.Errorf(codes.NotFound, "no entry for key %q", req.Key)
+	}
+	return e, nil
+}
+
+// FoldIn computes the U features of a user from a list of ratings without
+// training the model.
+func (p *Predictor) FoldIn(ctx context.Context, req *FoldInRequest) (*Features, error) {
+	u, err := FoldIn(p.model, req.Ratings, p.params, p.bias)
+	if err == errNotPositiveDefinite {
+		return nil, status.Errorf(codes.InvalidArgument, "ratings do not determine the features: %v", err)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if u == nil {
+		return nil, status.Error(codes.NotFound, "no features for the rated products")
+	}
+	return u, nil
+}
+
+// user returns the U features of a user. If the user has not been learnt yet,
+// the features are folded in from the given ratings.
+func (p *Predictor) user(ctx context.Context, userID string, ratings []*Rating) (*Features, error) {
+	if len(ratings) == 0 {
+		return p.features(userID, (*Entry).GetU)
+	}
+	e, err := p.model.Get(userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if u := e.GetU(); u != nil {
+		return u, nil
+	}
+	return p.FoldIn(ctx, &FoldInRequest{Ratings: ratings})
+}
+
+// features returns the U or P features of a key, or a NotFound error if the key
+// has not been learnt yet.
+func (p *Predictor) features(key string, get func(*Entry) *Features) (*Features, error) {
+	e, err := p.model.Get(key)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	f := get(e)
+	if f == nil {
+		return nil, status.Errorf(codes.NotFound, "no features for key %q", key)
+	}
+	return f, nil
+}
@@ -0,0 +1,99 @@
+package cofire
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startPredictor serves a Predictor for model in-process and returns a client
+// connected to it.
+func startPredictor(t *testing.T, model Model, opts ...PredictorOption) (PredictorClient, func()) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterPredictorServer(srv, NewPredictor(model, opts...))
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("error dialing predictor: %v", err)
+	}
+	return NewPredictorClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func testModel() MemoryModel {
+	return MemoryModel{
+		"user":  {U: makeFeatures([]float64{1.0, 0.0, 0.0})},
+		"prod1": {P: makeFeatures([]float64{1.0, 0.0, 0.0})},
+		"prod2": {P: makeFeatures([]float64{2.0, 0.0, 0.0})},
+		"prod3": {P: makeFeatures([]float64{3.0, 0.0, 0.0})},
+	}
+}
+
+func TestPredictor_Predict(t *testing.T) {
+	client, stop := startPredictor(t, testModel(), WithBias(0.5))
+	defer stop()
+	ctx := context.Background()
+
+	resp, err := client.Predict(ctx, &PredictRequest{UserId: "user", ProductId: "prod2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Score != 2.5 {
+		t.Errorf("score: %f, expected: 2.5", resp.Score)
+	}
+
+	_, err = client.Predict(ctx, &PredictRequest{UserId: "user", ProductId: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got: %v", err)
+	}
+}
+
+func TestPredictor_Recommend(t *testing.T) {
+	client, stop := startPredictor(t, testModel())
+	defer stop()
+
+	resp, err := client.Recommend(context.Background(), &RecommendRequest{UserId: "user", K: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recs := resp.Recommendations
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 recommendations, got %d", len(recs))
+	}
+	equals(t, recs[0].ProductId, "prod3")
+	equals(t, recs[1].ProductId, "prod2")
+}
+
+func TestPredictor_GetEntry(t *testing.T) {
+	client, stop := startPredictor(t, testModel())
+	defer stop()
+	ctx := context.Background()
+
+	e, err := client.GetEntry(ctx, &GetEntryRequest{Key: "prod1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.P.Rank() != DefaultParams().Rank {
+		t.Errorf("unexpected entry: %v", e)
+	}
+
+	_, err = client.GetEntry(ctx, &GetEntryRequest{Key: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RankingEvaluator evaluates the ranking quality of a model with a set of
+// held-out ratings. For each user with U features, all products with P
+// features are ranked by predicted score and compared with the products the
+// user rated in the held-out set.
+type RankingEvaluator struct {
+	// K is the number of top ranked products evaluated by the @K metrics.
+	K int
+	// Threshold is the minimum held-out score for a product to be relevant.
+	Threshold float64
+	// Bias is the global bias added to the predicted scores.
+	Bias float64
+	// Train are the ratings used for training the model. Products rated in
+	// Train are excluded from the ranking of the respective user.
+	Train []Rating
+}
+
+// RankingMetrics are ranking metrics averaged over the evaluated users.
+type RankingMetrics struct {
+	// K is the number of top ranked products evaluated.
+	K int
+	// Precision is the fraction of relevant products in the top K.
+	Precision float64
+	// Recall is the fraction of relevant products found in the top K.
+	Recall float64
+	// NDCG is the normalized discounted cumulative gain of the top K.
+	NDCG float64
+	// MAP is the mean average precision of the top K.
+	MAP float64
+	// AUC is the probability that a relevant product is ranked higher than an
+	// irrelevant one.
+	AUC float64
+	// Users is the number of users evaluated.
+	Users int
+}
+
+func (m *RankingMetrics) String() string {
+	return fmt.Sprintf("Precision@%d: %.6f Recall@%d: %.6f NDCG@%d: %.6f MAP: %.6f AUC: %.6f Users: %d",
+		m.K, m.Precision, m.K, m.Recall, m.K, m.NDCG, m.MAP, m.AUC, m.Users)
+}
+
+type rankedProduct struct {
+	id    string
+	p     *Features
+	score float64
+}
+
+// Evaluate computes the ranking metrics of the model with the held-out
+// ratings in test. Users without U features or without relevant products are
+// not evaluated.
+func (ev *RankingEvaluator) Evaluate(m Model, test []Rating) (*RankingMetrics, error) {
+	if ev.K <= 0 {
+		return nil, fmt.Errorf("invalid K: %d", ev.K)
+	}
+	var (
+		relevant = make(map[string]map[string]bool)
+		train    = make(map[string]map[string]bool)
+		products []rankedProduct
+		metrics  = &RankingMetrics{K: ev.K}
+		aucUsers int
+	)
+	for _, r := range test {
+		if r.Score < ev.Threshold {
+			continue
+		}
+		if relevant[r.UserId] == nil {
+			relevant[r.UserId] = make(map[string]bool)
+		}
+		relevant[r.UserId][r.ProductId] = true
+	}
+	for _, r := range ev.Train {
+		if train[r.UserId] == nil {
+			train[r.UserId] = make(map[string]bool)
+		}
+		train[r.UserId][r.ProductId] = true
+	}
+	err := m.Range(func(key string, e *Entry) bool {
+		if e.P != nil {
+			products = append(products, rankedProduct{id: key, p: e.P})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranking := make([]rankedProduct, 0, len(products))
+	for user, rel := range relevant {
+		e, err := m.Get(user)
+		if err != nil {
+			return nil, err
+		}
+		u := e.GetU()
+		if u == nil {
+			continue
+		}
+
+		// rank products not used for training by descending score
+		ranking = ranking[:0]
+		for _, p := range products {
+			if train[user][p.id] {
+				continue
+			}
+			p.score = u.Predict(p.p, ev.Bias)
+			ranking = append(ranking, p)
+		}
+		sort.Slice(ranking, func(i, j int) bool {
+			return ranking[i].score > ranking[j].score
+		})
+
+		if ev.add(metrics, ranking, rel) {
+			aucUsers++
+		}
+	}
+
+	if metrics.Users > 0 {
+		n := float64(metrics.Users)
+		metrics.Precision /= n
+		metrics.Recall /= n
+		metrics.NDCG /= n
+		metrics.MAP /= n
+	}
+	if aucUsers > 0 {
+		metrics.AUC /= float64(aucUsers)
+	}
+	return metrics, nil
+}
+
+// add adds the metrics of a single user's ranking to the sums in metrics. It
+// returns whether the AUC was defined for the user, ie, whether the ranking
+// contains relevant and irrelevant products.
+func (ev *RankingEvaluator) add(metrics *RankingMetrics, ranking []rankedProduct, relevant map[string]bool) bool {
+	var (
+		hits, dcg, idcg, ap float64
+		nrel, nirrel        float64
+		pairs, irrelAbove   float64
+	)
+	for _, p := range ranking {
+		if relevant[p.id] {
+			nrel++
+		} else {
+			nirrel++
+		}
+	}
+	for i, p := range ranking {
+		if !relevant[p.id] {
+			irrelAbove++
+			continue
+		}
+		pairs += irrelAbove
+		if i < ev.K {
+			hits++
+			dcg += 1 / math.Log2(float64(i+2))
+			ap += hits / float64(i+1)
+		}
+	}
+	for i := 0; i < ev.K && i < len(relevant); i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	metrics.Users++
+	metrics.Precision += hits / float64(ev.K)
+	metrics.Recall += hits / float64(len(relevant))
+	if idcg > 0 {
+		metrics.NDCG += dcg / idcg
+	}
+	metrics.MAP += ap / math.Min(float64(ev.K), float64(len(relevant)))
+	if nrel == 0 || nirrel == 0 {
+		return false
+	}
+	// pairs counts the irrelevant products ranked above relevant ones
+	metrics.AUC += 1 - pairs/(nrel*nirrel)
+	return true
+}
@@ -0,0 +1,48 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRankingEvaluator(t *testing.T) {
+	model := MemoryModel{
+		"user":  {U: makeFeatures([]float64{1.0})},
+		"other": {U: makeFeatures([]float64{1.0})},
+	}
+	for i := 1; i <= 6; i++ {
+		model[fmt.Sprintf("p%d", i)] = &Entry{P: makeFeatures([]float64{float64(7 - i)})}
+	}
+
+	ev := &RankingEvaluator{
+		K:         2,
+		Threshold: 3,
+		// p1 was used for training, so the ranking is p2, p3, p4, p5, p6
+		Train: []Rating{{UserId: "user", ProductId: "p1", Score: 5}},
+	}
+	m, err := ev.Evaluate(model, []Rating{
+		{UserId: "user", ProductId: "p2", Score: 5},
+		{UserId: "user", ProductId: "p4", Score: 4},
+		{UserId: "user", ProductId: "p5", Score: 1}, // not relevant
+		{UserId: "unknown", ProductId: "p1", Score: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	check := func(name string, actual, expected float64) {
+		if math.Abs(actual-expected) > 1e-6 {
+			t.Errorf("%s: %f, expected: %f", name, actual, expected)
+		}
+	}
+	if m.Users != 1 {
+		t.Errorf("users: %d, expected: 1", m.Users)
+	}
+	check("precision", m.Precision, 0.5)
+	check("recall", m.Recall, 0.5)
+	check("ndcg", m.NDCG, 1/(1+1/math.Log2(3)))
+	check("map", m.MAP, 0.5)
+	// p3 is ranked above p4, p2 above all irrelevant products
+	check("auc", m.AUC, 1-1.0/6)
+}
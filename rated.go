@@ -0,0 +1,52 @@
+package cofire
+
+import (
+	"time"
+)
+
+// addRated adds a product rated at ts to the rated products of the entry. A
+// product rated again is moved to the end of the list. Afterwards, products
+// rated before ts-maxAge and the oldest products exceeding max are dropped; zero
+// values disable the respective bound.
+func (e *Entry) addRated(product string, ts time.Time, max int, maxAge time.Duration) {
+	var (
+		now   = ts.UnixNano() / int64(time.Millisecond)
+		rated = e.Rated[:0]
+	)
+	for _, r := range e.Rated {
+		if r.ProductId == product {
+			continue
+		}
+		if maxAge > 0 && r.Timestamp < now-int64(maxAge/time.Millisecond) {
+			continue
+		}
+		rated = append(rated, r)
+	}
+	rated = append(rated, &Rated{ProductId: product, Timestamp: now})
+	if max > 0 && len(rated) > max {
+		rated = rated[len(rated)-max:]
+	}
+	e.Rated = rated
+}
+
+// HasRated returns whether the product is in the rated products of the entry.
+func (e *Entry) HasRated(product string) bool {
+	for _, r := range e.GetRated() {
+		if r.ProductId == product {
+			return true
+		}
+	}
+	return false
+}
+
+// ratedSet returns the products rated in the entry and in ratings as a set.
+func ratedSet(e *Entry, ratings []*Rating) map[string]bool {
+	rated := make(map[string]bool, len(e.GetRated())+len(ratings))
+	for _, r := range e.GetRated() {
+		rated[r.ProductId] = true
+	}
+	for _, r := range ratings {
+		rated[r.ProductId] = true
+	}
+	return rated
+}
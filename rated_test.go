@@ -0,0 +1,50 @@
+package cofire
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEntry_AddRated(t *testing.T) {
+	var (
+		e     = new(Entry)
+		start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	e.addRated("a", start, 3, 0)
+	e.addRated("b", start.Add(time.Minute), 3, 0)
+	e.addRated("c", start.Add(2*time.Minute), 3, 0)
+	e.addRated("a", start.Add(3*time.Minute), 3, 0)
+	e.addRated("d", start.Add(4*time.Minute), 3, 0)
+	if len(e.Rated) != 3 || e.HasRated("b") || !e.HasRated("a") {
+		t.Errorf("unexpected rated products: %v", e.Rated)
+	}
+
+	// products older than maxAge are dropped
+	e.addRated("e", start.Add(time.Hour), 0, 30*time.Minute)
+	if len(e.Rated) != 1 || !e.HasRated("e") {
+		t.Errorf("unexpected rated products: %v", e.Rated)
+	}
+}
+
+func TestPredictor_RecommendExcludesRated(t *testing.T) {
+	var (
+		model = testModel()
+		ctx   = context.Background()
+		p     = NewPredictor(model)
+	)
+	model["user"].addRated("prod3", time.Now(), 10, 0)
+
+	resp, err := p.Recommend(ctx, &RecommendRequest{UserId: "user", K: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equals(t, resp.Recommendations[0].ProductId, "prod2")
+
+	resp, err = p.Recommend(ctx, &RecommendRequest{UserId: "user", K: 1, IncludeRated: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	equals(t, resp.Recommendations[0].ProductId, "prod3")
+}
@@ -0,0 +1,142 @@
+package ratings
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+// CSVReader reads ratings from comma- or tab-separated values.
+type CSVReader struct {
+	r *csv.Reader
+
+	user, product, score int
+	timestamp            int
+	unit                 time.Duration
+	header               bool
+	records              int
+}
+
+// CSVOption configures a CSVReader.
+type CSVOption func(*CSVReader)
+
+// WithComma sets the field delimiter, eg, '\t' for TSV. The default is ','.
+func WithComma(comma rune) CSVOption {
+	return func(r *CSVReader) {
+		r.r.Comma = comma
+	}
+}
+
+// WithColumns sets the zero-based columns of user, product, score and
+// timestamp. A negative timestamp column means that there is no timestamp.
+// The default columns are 0, 1 and 2 without a timestamp, so that further
+// columns are ignored. Missing trailing timestamp columns are accepted.
+func WithColumns(user, product, score, timestamp int) CSVOption {
+	return func(r *CSVReader) {
+		r.user = user
+		r.product = product
+		r.score = score
+		r.timestamp = timestamp
+	}
+}
+
+// WithTimeUnit sets the unit of integer timestamps. The default is seconds.
+func WithTimeUnit(unit time.Duration) CSVOption {
+	return func(r *CSVReader) {
+		r.unit = unit
+	}
+}
+
+// WithHeader skips the first line of the input. Without this option, the
+// first line is skipped only if its score column holds a column name such as
+// "score" or "rating" (see isHeader).
+func WithHeader() CSVOption {
+	return func(r *CSVReader) {
+		r.header = true
+	}
+}
+
+// NewCSVReader creates a CSVReader.
+func NewCSVReader(r io.Reader, opts ...CSVOption) *CSVReader {
+	c := &CSVReader{
+		r:         csv.NewReader(r),
+		product:   1,
+		score:     2,
+		timestamp: -1,
+		unit:      time.Second,
+	}
+	c.r.FieldsPerRecord = -1
+	c.r.LazyQuotes = true
+	c.r.ReuseRecord = true
+	c.r.Comment = '#'
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Read returns the next rating.
+func (c *CSVReader) Read() (*cofire.Rating, error) {
+	for {
+		rec, err := c.r.Read()
+		if err != nil {
+			// io.EOF or a *csv.ParseError with the line
+			return nil, err
+		}
+		c.records++
+		if c.records == 1 && (c.header || c.isHeader(rec)) {
+			continue
+		}
+		r, err := c.parse(rec)
+		if err != nil {
+			line, _ := c.r.FieldPos(0)
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		return r, nil
+	}
+}
+
+// scoreNames are the names of score columns recognized in headers.
+var scoreNames = map[string]bool{
+	"score":  true,
+	"rating": true,
+	"stars":  true,
+	"value":  true,
+}
+
+// isHeader returns whether a record is a header, ie, whether its score column
+// holds the name of a score column. Other non-numeric scores are parse errors.
+func (c *CSVReader) isHeader(rec []string) bool {
+	if c.score >= len(rec) {
+		return false
+	}
+	return scoreNames[strings.ToLower(strings.TrimSpace(rec[c.score]))]
+}
+
+func (c *CSVReader) parse(rec []string) (*cofire.Rating, error) {
+	for _, col := range []int{c.user, c.product, c.score} {
+		if col >= len(rec) {
+			return nil, fmt.Errorf("expected at least %d columns, got %d", col+1, len(rec))
+		}
+	}
+	score, err := strconv.ParseFloat(rec[c.score], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid score %q", rec[c.score])
+	}
+	r := &cofire.Rating{
+		UserId:    rec[c.user],
+		ProductId: rec[c.product],
+		Score:     score,
+	}
+	if c.timestamp >= 0 && c.timestamp < len(rec) && rec[c.timestamp] != "" {
+		if r.Timestamp, err = parseTimestamp(rec[c.timestamp], c.unit); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
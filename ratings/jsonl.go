@@ -0,0 +1,87 @@
+package ratings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+// JSONLinesReader reads ratings from JSON objects, one per line, with the
+// fields user_id, product_id, score and the optional timestamp in unix
+// milliseconds. IDs may be strings or numbers.
+type JSONLinesReader struct {
+	l *lines
+}
+
+// NewJSONLinesReader creates a JSONLinesReader.
+func NewJSONLinesReader(r io.Reader) *JSONLinesReader {
+	return &JSONLinesReader{l: newLines(r)}
+}
+
+type jsonRating struct {
+	UserID    json.RawMessage `json:"user_id"`
+	ProductID json.RawMessage `json:"product_id"`
+	Score     *float64        `json:"score"`
+	Timestamp json.RawMessage `json:"timestamp"`
+}
+
+// Read returns the next rating.
+func (j *JSONLinesReader) Read() (*cofire.Rating, error) {
+	line, err := j.l.next()
+	if err != nil {
+		return nil, err
+	}
+	var jr jsonRating
+	if err := json.Unmarshal([]byte(line), &jr); err != nil {
+		return nil, j.l.error(err)
+	}
+	if jr.Score == nil {
+		return nil, j.l.error(fmt.Errorf("missing score"))
+	}
+	r := &cofire.Rating{Score: *jr.Score}
+	if r.UserId, err = jsonID(jr.UserID, "user_id"); err != nil {
+		return nil, j.l.error(err)
+	}
+	if r.ProductId, err = jsonID(jr.ProductID, "product_id"); err != nil {
+		return nil, j.l.error(err)
+	}
+	if len(jr.Timestamp) > 0 && string(jr.Timestamp) != "null" {
+		var ts interface{}
+		if err := json.Unmarshal(jr.Timestamp, &ts); err != nil {
+			return nil, j.l.error(err)
+		}
+		if r.Timestamp, err = parseTimestamp(fmt.Sprint(jsonNumber(ts)), time.Millisecond); err != nil {
+			return nil, j.l.error(err)
+		}
+	}
+	return r, nil
+}
+
+// jsonID returns a string or number ID.
+func jsonID(raw json.RawMessage, field string) (string, error) {
+	var id interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return "", err
+		}
+	}
+	switch v := id.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return fmt.Sprint(jsonNumber(v)), nil
+	}
+	return "", fmt.Errorf("missing or invalid %s", field)
+}
+
+// jsonNumber returns integral floats as integers, so they are formatted
+// without exponent.
+func jsonNumber(v interface{}) interface{} {
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package ratings
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// lines scans the non-empty lines of an input.
+type lines struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newLines(r io.Reader) *lines {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &lines{s: s}
+}
+
+// next returns the next non-empty line without surrounding whitespace. At
+// the end of the input, next returns io.EOF.
+func (l *lines) next() (string, error) {
+	for l.s.Scan() {
+		l.line++
+		if line := strings.TrimSpace(l.s.Text()); line != "" {
+			return line, nil
+		}
+	}
+	if err := l.s.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// error returns a ParseError for the current line.
+func (l *lines) error(err error) error {
+	return &ParseError{Line: l.line, Err: err}
+}
@@ -0,0 +1,50 @@
+package ratings
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+// MovieLensReader reads ratings from user::product::score::timestamp lines
+// as in the ratings.dat files of the MovieLens 1M and 10M datasets.
+// Timestamps are in seconds.
+type MovieLensReader struct {
+	l *lines
+}
+
+// NewMovieLensReader creates a MovieLensReader.
+func NewMovieLensReader(r io.Reader) *MovieLensReader {
+	return &MovieLensReader{l: newLines(r)}
+}
+
+// Read returns the next rating.
+func (m *MovieLensReader) Read() (*cofire.Rating, error) {
+	line, err := m.l.next()
+	if err != nil {
+		return nil, err
+	}
+	e := strings.Split(line, "::")
+	if len(e) < 3 {
+		return nil, m.l.error(fmt.Errorf("expected at least 3 fields, got %d", len(e)))
+	}
+	score, err := strconv.ParseFloat(e[2], 64)
+	if err != nil {
+		return nil, m.l.error(fmt.Errorf("invalid score %q", e[2]))
+	}
+	r := &cofire.Rating{
+		UserId:    e[0],
+		ProductId: e[1],
+		Score:     score,
+	}
+	if len(e) > 3 {
+		if r.Timestamp, err = parseTimestamp(e[3], time.Second); err != nil {
+			return nil, m.l.error(err)
+		}
+	}
+	return r, nil
+}
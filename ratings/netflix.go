@@ -0,0 +1,60 @@
+package ratings
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+// NetflixReader reads ratings in the format of the Netflix prize training
+// set: a line with the product ID followed by a colon, then one
+// user,score,date line per rating of the product.
+type NetflixReader struct {
+	l       *lines
+	product string
+}
+
+// NewNetflixReader creates a NetflixReader.
+func NewNetflixReader(r io.Reader) *NetflixReader {
+	return &NetflixReader{l: newLines(r)}
+}
+
+// Read returns the next rating.
+func (n *NetflixReader) Read() (*cofire.Rating, error) {
+	for {
+		line, err := n.l.next()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(line, ":") {
+			n.product = strings.TrimSuffix(line, ":")
+			continue
+		}
+		if n.product == "" {
+			return nil, n.l.error(fmt.Errorf("rating before product ID"))
+		}
+		e := strings.Split(line, ",")
+		if len(e) < 2 {
+			return nil, n.l.error(fmt.Errorf("expected at least 2 fields, got %d", len(e)))
+		}
+		score, err := strconv.ParseFloat(e[1], 64)
+		if err != nil {
+			return nil, n.l.error(fmt.Errorf("invalid score %q", e[1]))
+		}
+		r := &cofire.Rating{
+			UserId:    e[0],
+			ProductId: n.product,
+			Score:     score,
+		}
+		if len(e) > 2 {
+			if r.Timestamp, err = parseTimestamp(e[2], time.Second); err != nil {
+				return nil, n.l.error(err)
+			}
+		}
+		return r, nil
+	}
+}
@@ -0,0 +1,143 @@
+// Package ratings reads ratings from common dataset formats. Readers stream
+// the ratings one at a time, so that datasets need not fit in memory.
+package ratings
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+// Reader reads ratings one at a time.
+type Reader interface {
+	// Read returns the next rating. At the end of the input, Read returns
+	// io.EOF.
+	Read() (*cofire.Rating, error)
+}
+
+// ParseError is returned for a line that cannot be parsed.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ReadAll reads all ratings of r.
+func ReadAll(r Reader) ([]cofire.Rating, error) {
+	var ratings []cofire.Rating
+	for {
+		rating, err := r.Read()
+		if err == io.EOF {
+			return ratings, nil
+		}
+		if err != nil {
+			return ratings, err
+		}
+		ratings = append(ratings, *rating)
+	}
+}
+
+// Format is a dataset format.
+type Format string
+
+// Supported formats.
+const (
+	// CSV are comma-separated user,product,score lines with an optional
+	// header. Further columns are ignored.
+	CSV Format = "csv"
+	// TSV are tab-separated user, product and score lines with an optional
+	// header. Further columns are ignored.
+	TSV Format = "tsv"
+	// MovieLens are user::product::score::timestamp lines as in the .dat
+	// files of the MovieLens datasets.
+	MovieLens Format = "movielens"
+	// JSONLines are JSON objects with user_id, product_id, score and
+	// timestamp fields, one per line.
+	JSONLines Format = "jsonl"
+	// Netflix is the format of the Netflix prize training set.
+	Netflix Format = "netflix"
+)
+
+// FormatOf guesses the format of a file by its extension: .csv, .tsv, .dat
+// (MovieLens), .jsonl or .json (JSON Lines), and .txt (Netflix).
+func FormatOf(name string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return CSV, nil
+	case ".tsv":
+		return TSV, nil
+	case ".dat":
+		return MovieLens, nil
+	case ".jsonl", ".json":
+		return JSONLines, nil
+	case ".txt":
+		return Netflix, nil
+	}
+	return "", fmt.Errorf("unknown format of %s", name)
+}
+
+// NewReader creates a reader of the format with default options.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case CSV:
+		return NewCSVReader(r), nil
+	case TSV:
+		return NewCSVReader(r, WithComma('\t')), nil
+	case MovieLens:
+		return NewMovieLensReader(r), nil
+	case JSONLines:
+		return NewJSONLinesReader(r), nil
+	case Netflix:
+		return NewNetflixReader(r), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// ReadFile reads all ratings of a file. If format is empty, it is guessed
+// from the file extension.
+func ReadFile(name string, format Format) ([]cofire.Rating, error) {
+	if format == "" {
+		var err error
+		if format, err = FormatOf(name); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := NewReader(f, format)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return ratings, nil
+}
+
+// parseTimestamp parses a timestamp as an integer in unit or as an RFC 3339
+// or YYYY-MM-DD date and returns it in unix milliseconds.
+func parseTimestamp(s string, unit time.Duration) (int64, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ts * int64(unit) / int64(time.Millisecond), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid timestamp %q", s)
+}
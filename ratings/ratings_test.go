@@ -0,0 +1,144 @@
+package ratings
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lovoo/cofire"
+)
+
+func check(t *testing.T, r Reader, expected []cofire.Rating) {
+	t.Helper()
+	actual, err := ReadAll(r)
+	if err != nil {
+		t.Fatalf("error reading ratings: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "userId,movieId,rating,timestamp\n1,10,4.5,964982703\n\n2,20,3,964982224\n"
+	check(t, NewCSVReader(strings.NewReader(input), WithHeader(), WithColumns(0, 1, 2, 3)), []cofire.Rating{
+		{UserId: "1", ProductId: "10", Score: 4.5, Timestamp: 964982703000},
+		{UserId: "2", ProductId: "20", Score: 3, Timestamp: 964982224000},
+	})
+
+	// tab separated with other columns and millisecond timestamps
+	input = "x\t5\t1\t2018-01-02\n# comment\ny\t1\t2\t1500\n"
+	check(t, NewCSVReader(strings.NewReader(input), WithComma('\t'), WithColumns(2, 0, 1, 3), WithTimeUnit(time.Millisecond)), []cofire.Rating{
+		{UserId: "1", ProductId: "x", Score: 5, Timestamp: time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)},
+		{UserId: "2", ProductId: "y", Score: 1, Timestamp: 1500},
+	})
+
+	// no timestamp
+	check(t, NewCSVReader(strings.NewReader("u,p,1\n"), WithColumns(0, 1, 2, -1)), []cofire.Rating{
+		{UserId: "u", ProductId: "p", Score: 1},
+	})
+
+	// by default, the header is detected and further columns are ignored
+	input = "user,product,score,tag\nu,p,1,new\nv,q,2,\n"
+	check(t, NewCSVReader(strings.NewReader(input)), []cofire.Rating{
+		{UserId: "u", ProductId: "p", Score: 1},
+		{UserId: "v", ProductId: "q", Score: 2},
+	})
+	check(t, NewCSVReader(strings.NewReader("u,p,1,new\n")), []cofire.Rating{
+		{UserId: "u", ProductId: "p", Score: 1},
+	})
+	check(t, NewCSVReader(strings.NewReader("userId,movieId,Rating\nu,p,1\n")), []cofire.Rating{
+		{UserId: "u", ProductId: "p", Score: 1},
+	})
+}
+
+func TestCSVReader_Error(t *testing.T) {
+	r := NewCSVReader(strings.NewReader("u,p,1\nu,p,x\n"))
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := r.Read()
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 {
+		t.Errorf("expected parse error in line 2, got %v", err)
+	}
+
+	// a malformed first line is no header
+	r = NewCSVReader(strings.NewReader("u,p,x\nu,p,1\n"))
+	if _, err := r.Read(); err == nil {
+		t.Errorf("expected parse error in line 1")
+	} else if pe, ok := err.(*ParseError); !ok || pe.Line != 1 {
+		t.Errorf("expected parse error in line 1, got %v", err)
+	}
+
+	r = NewCSVReader(strings.NewReader("u,p\n"))
+	if _, err := r.Read(); err == nil || err == io.EOF {
+		t.Errorf("expected error with missing column, got %v", err)
+	}
+}
+
+func TestMovieLensReader(t *testing.T) {
+	input := "1::1193::5::978300760\n1::661::3::978302109\n"
+	check(t, NewMovieLensReader(strings.NewReader(input)), []cofire.Rating{
+		{UserId: "1", ProductId: "1193", Score: 5, Timestamp: 978300760000},
+		{UserId: "1", ProductId: "661", Score: 3, Timestamp: 978302109000},
+	})
+
+	_, err := NewMovieLensReader(strings.NewReader("\n1::2\n")).Read()
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 {
+		t.Errorf("expected parse error in line 2, got %v", err)
+	}
+}
+
+func TestJSONLinesReader(t *testing.T) {
+	input := `{"user_id": "a", "product_id": 7, "score": 1.5, "timestamp": 1500}
+{"user_id": 1, "product_id": "b", "score": 2}
+{"user_id": "c", "product_id": "d", "score": 3, "timestamp": "2018-01-02"}
+`
+	check(t, NewJSONLinesReader(strings.NewReader(input)), []cofire.Rating{
+		{UserId: "a", ProductId: "7", Score: 1.5, Timestamp: 1500},
+		{UserId: "1", ProductId: "b", Score: 2},
+		{UserId: "c", ProductId: "d", Score: 3, Timestamp: time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)},
+	})
+
+	for _, line := range []string{`{"user_id": "a", "product_id": "b"}`, `{"product_id": "b", "score": 1}`, `{`} {
+		if _, err := NewJSONLinesReader(strings.NewReader(line)).Read(); err == nil {
+			t.Errorf("expected error reading %s", line)
+		}
+	}
+}
+
+func TestNetflixReader(t *testing.T) {
+	input := "1:\n1488844,3,2005-09-06\n822109,5,2005-05-13\n2:\n2059652,4,2005-09-05\n"
+	date := func(y, m, d int) int64 {
+		return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+	}
+	check(t, NewNetflixReader(strings.NewReader(input)), []cofire.Rating{
+		{UserId: "1488844", ProductId: "1", Score: 3, Timestamp: date(2005, 9, 6)},
+		{UserId: "822109", ProductId: "1", Score: 5, Timestamp: date(2005, 5, 13)},
+		{UserId: "2059652", ProductId: "2", Score: 4, Timestamp: date(2005, 9, 5)},
+	})
+
+	if _, err := NewNetflixReader(strings.NewReader("1,2,2005-01-01\n")).Read(); err == nil {
+		t.Errorf("expected error for rating without product")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, format := range map[string]Format{
+		"ratings.csv":   CSV,
+		"ratings.TSV":   TSV,
+		"ratings.dat":   MovieLens,
+		"ratings.jsonl": JSONLines,
+		"mv_001.txt":    Netflix,
+	} {
+		f, err := FormatOf(name)
+		if err != nil || f != format {
+			t.Errorf("unexpected format of %s: %s (%v)", name, f, err)
+		}
+	}
+	if _, err := FormatOf("ratings"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
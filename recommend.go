@@ -0,0 +1,83 @@
+package cofire
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// TopK returns the k products of the model with the highest predicted score
+// for the user features u. The recommendations are sorted by descending score.
+// Products for which exclude returns true are skipped; exclude may be nil.
+func TopK(m Model, u *Features, bias float64, k int, exclude func(product string) bool) ([]*Recommendation, error) {
+	if u == nil || k <= 0 {
+		return nil, nil
+	}
+
+	top := newTopRecommendations(k)
+	err := m.Range(func(key string, e *Entry) bool {
+		if e.P != nil && (exclude == nil || !exclude(key)) {
+			top.offer(key, u.Predict(e.P, bias))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top.sorted(), nil
+}
+
+// topRecommendations keeps the k recommendations with the highest scores
+// offered to it.
+type topRecommendations struct {
+	k int
+	h recommendations
+}
+
+func newTopRecommendations(k int) *topRecommendations {
+	return &topRecommendations{k: k, h: make(recommendations, 0, k)}
+}
+
+// offer adds a product to the top recommendations if its score is high enough.
+func (t *topRecommendations) offer(product string, score float64) {
+	r := &Recommendation{ProductId: product, Score: score}
+	if len(t.h) < t.k {
+		heap.Push(&t.h, r)
+	} else if t.h.less(t.h[0], r) {
+		t.h[0] = r
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// sorted returns the top recommendations sorted by descending score.
+func (t *topRecommendations) sorted() []*Recommendation {
+	sort.Sort(sort.Reverse(t.h))
+	return t.h
+}
+
+// recommendations is a min-heap of recommendations ordered by score.
+type recommendations []*Recommendation
+
+func (h recommendations) Len() int           { return len(h) }
+func (h recommendations) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h recommendations) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// less orders by score and breaks ties by product id, so that results are
+// deterministic.
+func (h recommendations) less(a, b *Recommendation) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.ProductId > b.ProductId
+}
+
+func (h *recommendations) Push(x interface{}) {
+	*h = append(*h, x.(*Recommendation))
+}
+
+func (h *recommendations) Pop() interface{} {
+	old := *h
+	n := len(old)
+	r := old[n-1]
+	*h = old[:n-1]
+	return r
+}
@@ -0,0 +1,221 @@
+package cofire
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+const (
+	defaultRecsChange = 0.1
+	defaultRecsMaxAge = 24 * time.Hour
+)
+
+// recommender precomputes the recommendations of users whose U features
+// changed.
+type recommender struct {
+	model  Model
+	k      int
+	bias   float64
+	change float64
+	maxAge time.Duration
+	codec  goka.Codec
+
+	refresh goka.Stream
+}
+
+// RecommenderOption configures the recommender processor.
+type RecommenderOption func(*recommender)
+
+// WithRecsK sets the number of recommendations computed per user. By default
+// 10 recommendations are computed.
+func WithRecsK(k int) RecommenderOption {
+	return func(r *recommender) {
+		r.k = k
+	}
+}
+
+// WithRecsBias sets the global bias added to the predicted scores.
+func WithRecsBias(bias float64) RecommenderOption {
+	return func(r *recommender) {
+		r.bias = bias
+	}
+}
+
+// WithRecsChange sets the relative change of the U features, ie,
+// |u-u'|/|u'|, which triggers a recomputation. By default it is 0.1.
+func WithRecsChange(change float64) RecommenderOption {
+	return func(r *recommender) {
+		r.change = change
+	}
+}
+
+// WithRecsMaxAge sets the age after which the recommendations of a user are
+// recomputed, independent of how much U changed. Recommendations older than
+// maxAge are recomputed on the next update of the user, and a processor
+// created with NewRecsRefresher recomputes them after maxAge if the user is
+// not updated. By default the age is 24 hours; zero disables the
+// recomputation by age.
+func WithRecsMaxAge(maxAge time.Duration) RecommenderOption {
+	return func(r *recommender) {
+		r.maxAge = maxAge
+	}
+}
+
+// WithRecsCodec sets the codec of the entries in the learner's group table,
+// which has to match the learner's table codec (see WithTableCodec). The
+// default is EntryCodec.
+func WithRecsCodec(c goka.Codec) RecommenderOption {
+	return func(r *recommender) {
+		r.codec = c
+	}
+}
+
+// NewRecommender returns the GroupGraph for a processor that precomputes the
+// top-k recommendations of active users. The processor consumes the updates of
+// the learner's group table and recomputes the recommendations of a user with
+// model whenever the user's U features changed significantly or the
+// recommendations are too old. Products kept in the user's entry as rated are
+// excluded.
+//
+// Every computation of recommendations emits a refresh request into the
+// "<group>-recs-refresh" topic, which the processor of NewRecsRefresher delays
+// by the maximum age and sends back via the "<group>-recs-loop" topic. The
+// recommendations of a user are then recomputed with the user's entry in model
+// unless they were recomputed in the meantime, so that new products reach
+// users without updates.
+//
+// The recommendations are stored in the table of the "<group>-recs" group, ie,
+// in the "<group>-recs-table" topic, so that frontends can serve them with a
+// single Get on a view of goka.GroupTable("<group>-recs"). The model is
+// typically a view of the learner's group table (see NewViewModel).
+func NewRecommender(cofireGroup goka.Group, model Model, opts ...RecommenderOption) *goka.GroupGraph {
+	var (
+		group   = fmt.Sprintf("%s-recs", cofireGroup)
+		input   = goka.GroupTable(cofireGroup)
+		refresh = fmt.Sprintf("%s-recs-refresh", cofireGroup)
+		loop    = fmt.Sprintf("%s-recs-loop", cofireGroup)
+	)
+	r := &recommender{
+		model:   model,
+		k:       defaultRecommendations,
+		change:  defaultRecsChange,
+		maxAge:  defaultRecsMaxAge,
+		codec:   new(EntryCodec),
+		refresh: goka.Stream(refresh),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return goka.DefineGroup(goka.Group(group),
+		goka.Input(goka.Stream(input), r.codec, r.update),
+		goka.Input(goka.Stream(loop), new(RecommendationsCodec), r.refreshUser),
+		goka.Output(goka.Stream(refresh), new(RecommendationsCodec)),
+		goka.Persist(new(RecommendationsCodec)),
+	)
+}
+
+// NewRecsRefresher returns the GroupGraph for a processor that delays the
+// refresh requests of the recommender processor of the learner's group by
+// maxAge, which should match the recommender's WithRecsMaxAge.
+func NewRecsRefresher(cofireGroup goka.Group, maxAge time.Duration) *goka.GroupGraph {
+	var (
+		group = fmt.Sprintf("%s-recs-refresh", cofireGroup)
+		input = fmt.Sprintf("%s-recs-refresh", cofireGroup)
+		loop  = fmt.Sprintf("%s-recs-loop", cofireGroup)
+	)
+	return goka.DefineGroup(goka.Group(group),
+		goka.Input(
+			goka.Stream(input),
+			new(RecommendationsCodec),
+			refeed(goka.Stream(loop), maxAge, waiter, nil),
+		),
+		goka.Output(goka.Stream(loop), new(RecommendationsCodec)),
+	)
+}
+
+// update recomputes the recommendations of a user if needed.
+func (r *recommender) update(ctx goka.Context, m interface{}) {
+	e, ok := m.(*Entry)
+	if !ok || e.U == nil {
+		return
+	}
+	recs, _ := ctx.Value().(*Recommendations)
+	if !r.outdated(recs, e.U, ctx.Timestamp()) {
+		return
+	}
+	r.recompute(ctx, e)
+}
+
+// refreshUser recomputes the recommendations of a user when a refresh
+// request returns, which carries the timestamp of the recommendations it was
+// emitted for.
+func (r *recommender) refreshUser(ctx goka.Context, m interface{}) {
+	req, ok := m.(*Recommendations)
+	if !ok {
+		return
+	}
+	recs, _ := ctx.Value().(*Recommendations)
+	if recs == nil || recs.Timestamp != req.Timestamp {
+		// recomputed in the meantime, which requested another refresh
+		return
+	}
+	e, err := r.model.Get(ctx.Key())
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	if e.GetU() == nil {
+		return
+	}
+	r.recompute(ctx, e)
+}
+
+// recompute computes and stores the recommendations of the user entry e and
+// requests their refresh.
+func (r *recommender) recompute(ctx goka.Context, e *Entry) {
+	rated := ratedSet(e, nil)
+	top, err := TopK(r.model, e.U, r.bias, r.k, func(product string) bool {
+		return rated[product]
+	})
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ts := ctx.Timestamp().UnixNano() / int64(time.Millisecond)
+	ctx.SetValue(&Recommendations{
+		Recommendations: top,
+		Timestamp:       ts,
+		U:               e.U,
+	})
+	if r.maxAge > 0 {
+		ctx.Emit(r.refresh, ctx.Key(), &Recommendations{Timestamp: ts})
+	}
+}
+
+// outdated returns whether the recommendations have to be recomputed for the
+// features u at time ts.
+func (r *recommender) outdated(recs *Recommendations, u *Features, ts time.Time) bool {
+	if recs == nil || recs.U.Rank() != u.Rank() {
+		return true
+	}
+	if r.maxAge > 0 && ts.Sub(time.Unix(0, recs.Timestamp*int64(time.Millisecond))) > r.maxAge {
+		return true
+	}
+	return relativeChange(u, recs.U) > r.change
+}
+
+// relativeChange returns |f-o|/|o|.
+func relativeChange(f, o *Features) float64 {
+	var d, n float64
+	for i := range o.V {
+		d += (f.V[i] - o.V[i]) * (f.V[i] - o.V[i])
+		n += o.V[i] * o.V[i]
+	}
+	if n == 0 {
+		return math.Inf(1)
+	}
+	return math.Sqrt(d / n)
+}
@@ -0,0 +1,126 @@
+package cofire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lovoo/goka"
+)
+
+func TestRecommender(t *testing.T) {
+	var (
+		r = &recommender{
+			model:  testModel(),
+			k:      2,
+			change: 0.1,
+			maxAge: time.Hour,
+		}
+		ctx = &mockContext{ts: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+		u   = makeFeatures([]float64{1.0, 0.0, 0.0})
+	)
+	ctx.emitCheck = func(goka.Stream, string, interface{}) {}
+
+	recs := func() *Recommendations {
+		return ctx.value.(*Recommendations)
+	}
+
+	// entries without U are ignored
+	r.update(ctx, &Entry{P: u})
+	if ctx.value != nil {
+		t.Fatalf("unexpected recommendations: %v", ctx.value)
+	}
+
+	r.update(ctx, &Entry{U: u, Rated: []*Rated{{ProductId: "prod3"}}})
+	if len(recs().Recommendations) != 2 {
+		t.Fatalf("unexpected recommendations: %v", recs())
+	}
+	equals(t, recs().Recommendations[0].ProductId, "prod2")
+	computed := recs()
+
+	// small changes of U do not recompute recommendations
+	ctx.ts = ctx.ts.Add(time.Minute)
+	r.update(ctx, &Entry{U: makeFeatures([]float64{1.05, 0.0, 0.0})})
+	if recs() != computed {
+		t.Errorf("recommendations unexpectedly recomputed")
+	}
+
+	// large changes of U do
+	r.update(ctx, &Entry{U: makeFeatures([]float64{2.0, 0.0, 0.0})})
+	if recs() == computed {
+		t.Errorf("recommendations not recomputed")
+	}
+	equals(t, recs().Recommendations[0].ProductId, "prod3")
+	computed = recs()
+
+	// old recommendations are recomputed
+	ctx.ts = ctx.ts.Add(2 * time.Hour)
+	r.update(ctx, &Entry{U: makeFeatures([]float64{2.0, 0.0, 0.0})})
+	if recs() == computed {
+		t.Errorf("recommendations not recomputed")
+	}
+}
+
+func TestRecommender_Refresh(t *testing.T) {
+	var (
+		model = testModel()
+		r     = &recommender{
+			model:   model,
+			k:       1,
+			change:  0.1,
+			maxAge:  time.Hour,
+			refresh: "refresh",
+		}
+		ctx      = &mockContext{ts: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+		requests []*Recommendations
+	)
+	ctx.emitCheck = func(s goka.Stream, k string, m interface{}) {
+		equals(t, string(s), "refresh")
+		equals(t, k, "key")
+		requests = append(requests, m.(*Recommendations))
+	}
+	recs := func() *Recommendations {
+		return ctx.value.(*Recommendations)
+	}
+
+	model["key"] = &Entry{U: makeFeatures([]float64{1.0, 0.0, 0.0})}
+	r.update(ctx, model["key"])
+	equals(t, recs().Recommendations[0].ProductId, "prod3")
+	if len(requests) != 1 || requests[0].Timestamp != recs().Timestamp {
+		t.Fatalf("expected refresh request for %d, got %v", recs().Timestamp, requests)
+	}
+
+	// the refresh request returns after maxAge without updates of the user
+	// and recomputes the recommendations with the new products
+	model["prod4"] = &Entry{P: makeFeatures([]float64{4.0, 0.0, 0.0})}
+	ctx.ts = ctx.ts.Add(time.Hour)
+	r.refreshUser(ctx, requests[0])
+	equals(t, recs().Recommendations[0].ProductId, "prod4")
+	if len(requests) != 2 || requests[1].Timestamp != recs().Timestamp {
+		t.Fatalf("expected another refresh request for %d, got %v", recs().Timestamp, requests)
+	}
+
+	// refresh requests of recommendations recomputed since are dropped
+	computed := recs()
+	ctx.ts = ctx.ts.Add(time.Hour)
+	r.refreshUser(ctx, requests[0])
+	if recs() != computed || len(requests) != 2 {
+		t.Errorf("stale refresh request recomputed recommendations")
+	}
+
+	// no refresh requests without maximum age
+	r.maxAge = 0
+	r.update(ctx, &Entry{U: makeFeatures([]float64{-1.0, 0.0, 0.0})})
+	if recs() == computed || len(requests) != 2 {
+		t.Errorf("expected recomputation without refresh request, got %v", requests)
+	}
+}
+
+func TestRecommender_Codec(t *testing.T) {
+	c := new(JSONEntryCodec)
+	gg := NewRecommender("test", testModel(), WithRecsCodec(c))
+	for _, e := range gg.InputStreams() {
+		if e.Topic() == "test-table" && e.Codec() != c {
+			t.Errorf("unexpected codec of %s: %T", e.Topic(), e.Codec())
+		}
+	}
+}
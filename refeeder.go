@@ -14,26 +14,45 @@ func waiter(t time.Time) <-chan time.Time {
 	return time.After(time.Until(t))
 }
 
-func refeed(loop goka.Stream, delay time.Duration, wait waitUntil) goka.ProcessCallback {
+func refeed(loop goka.Stream, delay time.Duration, wait waitUntil, metrics *Metrics) goka.ProcessCallback {
 	return func(ctx goka.Context, m interface{}) {
 		<-wait(ctx.Timestamp().Add(delay))
 		ctx.Emit(loop, ctx.Key(), m)
+		metrics.refeed(time.Since(ctx.Timestamp()))
+	}
+}
+
+// RefeederOption configures a refeeder.
+type RefeederOption func(*refeederOptions)
+
+type refeederOptions struct {
+	metrics *Metrics
+}
+
+// WithRefeederMetrics makes the refeeder record its metrics in m.
+func WithRefeederMetrics(m *Metrics) RefeederOption {
+	return func(o *refeederOptions) {
+		o.metrics = m
 	}
 }
 
 // NewRefeeder returns the GroupGraph for a processor that refeeds the input of
 // the learner after a specified delay.
-func NewRefeeder(cofireGroup goka.Group, delay time.Duration) *goka.GroupGraph {
+func NewRefeeder(cofireGroup goka.Group, delay time.Duration, opts ...RefeederOption) *goka.GroupGraph {
 	var (
 		group = fmt.Sprintf("%s-refeed", cofireGroup)
 		input = fmt.Sprintf("%s-refeed", cofireGroup)
 		loop  = fmt.Sprintf("%s-loop", cofireGroup)
+		o     refeederOptions
 	)
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return goka.DefineGroup(goka.Group(group),
 		goka.Input(
 			goka.Stream(input),
 			new(messageCodec),
-			refeed(goka.Stream(loop), delay, waiter),
+			refeed(goka.Stream(loop), delay, waiter, o.metrics),
 		),
 		goka.Output(goka.Stream(loop), new(messageCodec)),
 	)
@@ -9,6 +9,7 @@ import (
 
 type mockContext struct {
 	ts        time.Time
+	value     interface{}
 	emitCheck func(goka.Stream, string, interface{})
 }
 
@@ -19,10 +20,10 @@ func (c *mockContext) Join(goka.Table) interface{}                 { return nil
 func (c *mockContext) Lookup(goka.Table, string) interface{}       { return nil }
 func (c *mockContext) Key() string                                 { return "key" }
 func (c *mockContext) Loopback(string, interface{})                {}
-func (c *mockContext) SetValue(interface{})                        {}
+func (c *mockContext) SetValue(v interface{})                      { c.value = v }
 func (c *mockContext) Timestamp() time.Time                        { return c.ts }
 func (c *mockContext) Topic() goka.Stream                          { return "stream" }
-func (c *mockContext) Value() interface{}                          { return nil }
+func (c *mockContext) Value() interface{}                          { return c.value }
 
 func equals(t *testing.T, actual, expected string) {
 	if actual != expected {
@@ -44,7 +45,7 @@ func TestRefeeder(t *testing.T) {
 			t.Errorf("unexpected time: %v (%v)", ts, start)
 		}
 		return waiter(start) // dont wait anything since start already over
-	})
+	}, nil)
 
 	// the message was created at start - 10 seconds
 	ctx := new(mockContext)
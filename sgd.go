@@ -1,8 +1,12 @@
 package cofire
 
-import "sync"
+import (
+	"sync/atomic"
+	"unsafe"
+)
 
-// SGD is a helper to apply stochastic gradient descent.
+// SGD is a helper to apply stochastic gradient descent. It is safe for
+// concurrent use.
 type SGD struct {
 	// Gamma constant for learning speed
 	Gamma float64
@@ -10,11 +14,15 @@ type SGD struct {
 	// Lambda constant for regularization
 	Lambda float64
 
-	// average bias calculated in runtime
-	bias   float64
-	bsum   float64
-	bcount int
-	m      sync.RWMutex
+	// average bias calculated in runtime, a *biasSum replaced atomically
+	bias unsafe.Pointer
+}
+
+// biasSum is an immutable snapshot of the added biases and their average.
+type biasSum struct {
+	sum   float64
+	count int
+	bias  float64
 }
 
 // NewSGD returns a configured SGD helper.
@@ -25,18 +33,41 @@ func NewSGD(gamma, lambda float64) *SGD {
 // Add adds bias to the prediction error. If Add is called multiple times, the average bias
 // is computed.
 func (s *SGD) Add(bias float64) {
-	s.m.Lock()
-	s.bsum += bias
-	s.bcount++
-	s.bias = s.bsum / float64(s.bcount)
-	s.m.Unlock()
+	s.addSum(bias, 1)
 }
 
 // Bias returns the average bias added to the sgd object.
 func (s *SGD) Bias() float64 {
-	s.m.RLock()
-	defer s.m.RUnlock()
-	return s.bias
+	if b := (*biasSum)(atomic.LoadPointer(&s.bias)); b != nil {
+		return b.bias
+	}
+	return 0
+}
+
+// sum returns the sum and count of the biases added.
+func (s *SGD) sum() (float64, int) {
+	if b := (*biasSum)(atomic.LoadPointer(&s.bias)); b != nil {
+		return b.sum, b.count
+	}
+	return 0, 0
+}
+
+// addSum adds the sum of count biases without locking.
+func (s *SGD) addSum(sum float64, count int) {
+	for {
+		old := atomic.LoadPointer(&s.bias)
+		b := &biasSum{sum: sum, count: count}
+		if o := (*biasSum)(old); o != nil {
+			b.sum += o.sum
+			b.count += o.count
+		}
+		if b.count > 0 {
+			b.bias = b.sum / float64(b.count)
+		}
+		if atomic.CompareAndSwapPointer(&s.bias, old, unsafe.Pointer(b)) {
+			return
+		}
+	}
 }
 
 // Error computes the error between the score prediction (with f and o) and the
@@ -46,17 +77,45 @@ func (s *SGD) Error(f, o *Features, score float64) float64 {
 }
 
 // ApplyError applies the stochastic gradient descent on features f with o and
-// error e.
+// error e, and counts the update in f.
 func (s *SGD) ApplyError(f, o *Features, e float64) {
-	update := o.mult(e * s.Gamma)
-	regularization := f.mult(-s.Lambda * s.Gamma)
-
-	// update features
-	f.add(update)
-	f.add(regularization)
+	// update features with gradient and regularization
+	f.axpby(e*s.Gamma, o, -s.Lambda*s.Gamma)
 
 	// update bias
 	f.Bias += s.Gamma * (e - s.Lambda*f.Bias)
+	f.Updates++
+}
+
+// ApplyErrors applies one step of gradient descent on features f with the
+// sum of the gradients of errors es of f with os, and counts the updates in f.
+// If the features os are large or many, the step is damped from Gamma to
+// 1/sum(|o|^2+1), so that the step does not diverge.
+func (s *SGD) ApplyErrors(f *Features, os []*Features, es []float64) {
+	var (
+		g    = make([]float64, len(f.V))
+		gb   float64
+		norm float64
+	)
+	for i, o := range os {
+		for j := 0; j < len(g) && j < len(o.V); j++ {
+			g[j] += es[i] * o.V[j]
+		}
+		gb += es[i]
+		norm += o.dot(o) + 1
+	}
+	step := s.Gamma
+	if step*norm > 1 {
+		step = 1 / norm
+	}
+
+	// update features with the summed gradients and regularizations
+	n := float64(len(os))
+	for j, v := range f.V {
+		f.V[j] = v + step*(g[j]-n*s.Lambda*v)
+	}
+	f.Bias += step * (gb - n*s.Lambda*f.Bias)
+	f.Updates += uint64(len(os))
 }
 
 // Apply applies the stochastic gradient descent on features f with o and a
@@ -1,6 +1,8 @@
 package cofire
 
 import (
+	"math"
+	"sync"
 	"testing"
 )
 
@@ -21,3 +23,130 @@ func TestGradientDescent(t *testing.T) {
 		t.Errorf("a >= b (%f >= %f)", a, b)
 	}
 }
+
+func TestSGD_Bias(t *testing.T) {
+	var (
+		s   = NewSGD(0.01, 0.1)
+		sum float64
+	)
+	// the average is exact after every add
+	for i := 0; i < 1000; i++ {
+		s.Add(float64(i % 5))
+		sum += float64(i % 5)
+		if b := s.Bias(); math.Abs(b-sum/float64(i+1)) > 1e-12 {
+			t.Fatalf("bias %f after %d adds, expected %f", b, i+1, sum/float64(i+1))
+		}
+	}
+
+	// concurrent adds are all counted
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			f := NewFeatures(4).Randomize()
+			for i := 0; i < 1000; i++ {
+				s.Apply(f, f, float64(w))
+			}
+		}(w)
+	}
+	wg.Wait()
+	sum += 1000 * (0 + 1 + 2 + 3 + 4 + 5 + 6 + 7)
+	if b := s.Bias(); math.Abs(b-sum/float64(1000+8000)) > 1e-9 {
+		t.Errorf("bias %f, expected %f", b, sum/float64(1000+8000))
+	}
+}
+
+func TestSGD_ApplyErrors(t *testing.T) {
+	var (
+		s = NewSGD(0.01, 0.1)
+		f = makeFeatures([]float64{0.5, -0.5, 1})
+		o = makeFeatures([]float64{0.1, 0.2, 0.3})
+	)
+
+	// a batch of one error is a single update
+	single, batch := f.clone(), f.clone()
+	s.ApplyError(single, o, 0.7)
+	s.ApplyErrors(batch, []*Features{o}, []float64{0.7})
+	for i := range single.V {
+		if !near(single.V[i], batch.V[i]) {
+			t.Errorf("factor %d: expected %f, got %f", i, single.V[i], batch.V[i])
+		}
+	}
+	if !near(single.Bias, batch.Bias) || batch.Updates != 1 {
+		t.Errorf("expected %v, got %v", single, batch)
+	}
+
+	// features of a lower rank, eg, of a user not yet reinitialized, update
+	// the common factors
+	short := makeFeatures([]float64{0.1, 0.2})
+	single, batch = f.clone(), f.clone()
+	s.ApplyError(single, short, 0.7)
+	s.ApplyErrors(batch, []*Features{short}, []float64{0.7})
+	for i := range short.V {
+		if !near(single.V[i], batch.V[i]) {
+			t.Errorf("factor %d: expected %f, got %f", i, single.V[i], batch.V[i])
+		}
+	}
+
+	// large batches are damped instead of overshooting the scores, ie, the
+	// prediction of 10 plus the errors of 100
+	var (
+		os = make([]*Features, 1000)
+		es = make([]float64, 1000)
+	)
+	for i := range os {
+		os[i], es[i] = makeFeatures([]float64{10, 10, 10}), 100
+	}
+	batch = f.clone()
+	s.ApplyErrors(batch, os, es)
+	if p := batch.Predict(makeFeatures([]float64{10, 10, 10}), 0); p > 110+1e-9 || p < 100 || batch.Updates != 1000 {
+		t.Errorf("damped step overshoots: prediction %f, %d updates", p, batch.Updates)
+	}
+}
+
+// mutexSGD tracks the bias with a read-write mutex as SGD used to.
+type mutexSGD struct {
+	SGD
+	bias   float64
+	bsum   float64
+	bcount int
+	m      sync.RWMutex
+}
+
+func (s *mutexSGD) Apply(f, o *Features, score float64) {
+	s.m.Lock()
+	s.bsum += score
+	s.bcount++
+	s.bias = s.bsum / float64(s.bcount)
+	s.m.Unlock()
+
+	s.m.RLock()
+	bias := s.bias
+	s.m.RUnlock()
+	s.ApplyError(f, o, score-f.Predict(o, bias))
+}
+
+// BenchmarkSGD_Apply applies updates to different features from parallel
+// goroutines, eg, with -cpu 1,4,16.
+func BenchmarkSGD_Apply(b *testing.B) {
+	for _, tc := range []struct {
+		name  string
+		apply func(f, o *Features, score float64)
+	}{
+		{"atomic", NewSGD(0.001, 0.01).Apply},
+		{"mutex", (&mutexSGD{SGD: SGD{Gamma: 0.001, Lambda: 0.01}}).Apply},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				var (
+					f = NewFeatures(10).Randomize()
+					o = NewFeatures(10).Randomize()
+				)
+				for pb.Next() {
+					tc.apply(f, o, 3)
+				}
+			})
+		})
+	}
+}
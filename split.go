@@ -0,0 +1,176 @@
+package cofire
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Splitter splits ratings into a training and a test set. Both sets keep the
+// order of the input ratings.
+type Splitter interface {
+	Split(ratings []Rating) (train, test []Rating)
+}
+
+// RandomSplitter puts a random fraction of the ratings into the test set.
+// Like a shuffle, it ignores time, so the training set may contain ratings
+// newer than the test set.
+type RandomSplitter struct {
+	// TestFraction is the fraction of ratings in the test set.
+	TestFraction float64
+	// Seed seeds the random selection.
+	Seed int64
+}
+
+// Split splits the ratings.
+func (s *RandomSplitter) Split(ratings []Rating) (train, test []Rating) {
+	var (
+		rnd    = rand.New(rand.NewSource(s.Seed))
+		n      = testSize(len(ratings), s.TestFraction)
+		isTest = make([]bool, len(ratings))
+	)
+	for _, i := range rnd.Perm(len(ratings))[:n] {
+		isTest[i] = true
+	}
+	return splitBy(ratings, isTest)
+}
+
+// TemporalSplitter splits the ratings by their timestamp: newer ratings are
+// put into the test set, so the model is tested with ratings from after its
+// training.
+type TemporalSplitter struct {
+	// Cutoff is the time from which on ratings are put into the test set. If
+	// zero, the newest TestFraction of the ratings are put into the test set.
+	Cutoff time.Time
+	// TestFraction is the fraction of ratings in the test set if Cutoff is
+	// zero.
+	TestFraction float64
+}
+
+// Split splits the ratings.
+func (s *TemporalSplitter) Split(ratings []Rating) (train, test []Rating) {
+	isTest := make([]bool, len(ratings))
+	if !s.Cutoff.IsZero() {
+		cutoff := s.Cutoff.UnixNano() / int64(time.Millisecond)
+		for i, r := range ratings {
+			isTest[i] = r.Timestamp >= cutoff
+		}
+		return splitBy(ratings, isTest)
+	}
+
+	n := testSize(len(ratings), s.TestFraction)
+	for _, i := range byTime(ratings, allIndexes(len(ratings)))[len(ratings)-n:] {
+		isTest[i] = true
+	}
+	return splitBy(ratings, isTest)
+}
+
+// LeaveLastOutSplitter puts the N newest ratings of each user into the test
+// set. Users with N or fewer ratings are kept in the training set, as are all
+// ratings if N is not positive.
+type LeaveLastOutSplitter struct {
+	N int
+}
+
+// Split splits the ratings.
+func (s *LeaveLastOutSplitter) Split(ratings []Rating) (train, test []Rating) {
+	isTest := make([]bool, len(ratings))
+	if s.N <= 0 {
+		return splitBy(ratings, isTest)
+	}
+	for _, idx := range userIndexes(ratings) {
+		if len(idx) <= s.N {
+			continue
+		}
+		for _, i := range byTime(ratings, idx)[len(idx)-s.N:] {
+			isTest[i] = true
+		}
+	}
+	return splitBy(ratings, isTest)
+}
+
+// StratifiedSplitter puts a random fraction of the ratings of each user into
+// the test set, so that every user is represented in both sets in the same
+// proportion. At least one rating of each user is kept in the training set.
+type StratifiedSplitter struct {
+	// TestFraction is the fraction of each user's ratings in the test set.
+	TestFraction float64
+	// Seed seeds the random selection.
+	Seed int64
+}
+
+// Split splits the ratings.
+func (s *StratifiedSplitter) Split(ratings []Rating) (train, test []Rating) {
+	var (
+		rnd    = rand.New(rand.NewSource(s.Seed))
+		isTest = make([]bool, len(ratings))
+		users  = userIndexes(ratings)
+		ids    = make([]string, 0, len(users))
+	)
+	// iterate users in a fixed order for reproducible splits
+	for user := range users {
+		ids = append(ids, user)
+	}
+	sort.Strings(ids)
+	for _, user := range ids {
+		idx := users[user]
+		n := testSize(len(idx), s.TestFraction)
+		if n == len(idx) {
+			n--
+		}
+		for _, j := range rnd.Perm(len(idx))[:n] {
+			isTest[idx[j]] = true
+		}
+	}
+	return splitBy(ratings, isTest)
+}
+
+// testSize returns the number of test ratings of n ratings.
+func testSize(n int, fraction float64) int {
+	switch {
+	case fraction <= 0:
+		return 0
+	case fraction >= 1:
+		return n
+	}
+	return int(math.Round(float64(n) * fraction))
+}
+
+// splitBy splits the ratings into train and test keeping their order.
+func splitBy(ratings []Rating, isTest []bool) (train, test []Rating) {
+	for i, r := range ratings {
+		if isTest[i] {
+			test = append(test, r)
+		} else {
+			train = append(train, r)
+		}
+	}
+	return train, test
+}
+
+// userIndexes returns the indexes of the ratings of each user.
+func userIndexes(ratings []Rating) map[string][]int {
+	users := make(map[string][]int)
+	for i, r := range ratings {
+		users[r.UserId] = append(users[r.UserId], i)
+	}
+	return users
+}
+
+func allIndexes(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
+
+// byTime sorts the indexes by the timestamp of the ratings. Ratings with the
+// same timestamp keep their order.
+func byTime(ratings []Rating, idx []int) []int {
+	sort.SliceStable(idx, func(i, j int) bool {
+		return ratings[idx[i]].Timestamp < ratings[idx[j]].Timestamp
+	})
+	return idx
+}
@@ -0,0 +1,118 @@
+package cofire
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// splitRatings returns 4 ratings per user u0..u2 with timestamps 1..12 in
+// reverse order.
+func splitRatings() []Rating {
+	var ratings []Rating
+	for i := 0; i < 12; i++ {
+		ratings = append(ratings, Rating{
+			UserId:    fmt.Sprintf("u%d", i%3),
+			ProductId: fmt.Sprintf("p%d", i),
+			Timestamp: int64(12 - i),
+		})
+	}
+	return ratings
+}
+
+func products(ratings []Rating) string {
+	var s string
+	for _, r := range ratings {
+		s += r.ProductId + " "
+	}
+	return s
+}
+
+func checkSplit(t *testing.T, ratings, train, test []Rating) {
+	if len(train)+len(test) != len(ratings) {
+		t.Errorf("split lost ratings: %d + %d != %d", len(train), len(test), len(ratings))
+	}
+	seen := make(map[string]bool)
+	for _, r := range append(append([]Rating(nil), train...), test...) {
+		if seen[r.ProductId] {
+			t.Errorf("rating %s in both sets", r.ProductId)
+		}
+		seen[r.ProductId] = true
+	}
+}
+
+func TestRandomSplitter(t *testing.T) {
+	ratings := splitRatings()
+	train, test := (&RandomSplitter{TestFraction: 0.25, Seed: 1}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	if len(test) != 3 {
+		t.Errorf("unexpected test size: %d", len(test))
+	}
+	_, again := (&RandomSplitter{TestFraction: 0.25, Seed: 1}).Split(ratings)
+	equals(t, products(again), products(test))
+}
+
+func TestTemporalSplitter(t *testing.T) {
+	ratings := splitRatings()
+
+	train, test := (&TemporalSplitter{TestFraction: 0.25}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	equals(t, products(test), "p0 p1 p2 ")
+
+	cutoff := time.Unix(0, 10*int64(time.Millisecond))
+	train, test = (&TemporalSplitter{Cutoff: cutoff}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	equals(t, products(test), "p0 p1 p2 ")
+	equals(t, products(train), "p3 p4 p5 p6 p7 p8 p9 p10 p11 ")
+}
+
+func TestLeaveLastOutSplitter(t *testing.T) {
+	ratings := splitRatings()
+
+	train, test := (&LeaveLastOutSplitter{N: 1}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	equals(t, products(test), "p0 p1 p2 ")
+
+	train, test = (&LeaveLastOutSplitter{N: 2}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	equals(t, products(test), "p0 p1 p2 p3 p4 p5 ")
+
+	// users with too few ratings are kept in train
+	train, test = (&LeaveLastOutSplitter{N: 4}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	if len(test) != 0 {
+		t.Errorf("unexpected test ratings: %s", products(test))
+	}
+
+	// no test set without positive N
+	for _, n := range []int{0, -1} {
+		train, test = (&LeaveLastOutSplitter{N: n}).Split(ratings)
+		checkSplit(t, ratings, train, test)
+		if len(test) != 0 {
+			t.Errorf("N=%d: unexpected test ratings: %s", n, products(test))
+		}
+	}
+}
+
+func TestStratifiedSplitter(t *testing.T) {
+	ratings := splitRatings()
+
+	train, test := (&StratifiedSplitter{TestFraction: 0.5, Seed: 1}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	count := make(map[string]int)
+	for _, r := range test {
+		count[r.UserId]++
+	}
+	for _, u := range []string{"u0", "u1", "u2"} {
+		if count[u] != 2 {
+			t.Errorf("unexpected number of test ratings of %s: %d", u, count[u])
+		}
+	}
+
+	// at least one rating per user is kept in train
+	train, test = (&StratifiedSplitter{TestFraction: 1, Seed: 1}).Split(ratings)
+	checkSplit(t, ratings, train, test)
+	if len(train) != 3 {
+		t.Errorf("unexpected train size: %d", len(train))
+	}
+}
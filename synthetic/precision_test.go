@@ -0,0 +1,42 @@
+package synthetic
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lovoo/cofire"
+)
+
+// TestPrecision measures the accuracy impact of storing features with lower
+// precision. Run with -v to see the RMSE of each precision.
+func TestPrecision(t *testing.T) {
+	for _, tc := range []struct {
+		rank      int
+		tolerance map[cofire.Precision]float64
+	}{
+		{2, map[cofire.Precision]float64{cofire.Float32: 1e-3, cofire.Int8: 0.01}},
+		{10, map[cofire.Precision]float64{cofire.Float32: 1e-3, cofire.Int8: 0.02}},
+	} {
+		g := &Generator{Users: 200, Products: 100, Rank: tc.rank, Density: 0.3, Noise: 0.1, Seed: 1}
+		d, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			train, test = (&cofire.RandomSplitter{TestFraction: 0.1, Seed: 1}).Split(d.Ratings)
+			params      = cofire.Parameters{Rank: tc.rank, Gamma: 0.02, Lambda: 0.001, Iterations: 100}
+			base        float64
+		)
+		for _, p := range []cofire.Precision{cofire.Float64, cofire.Float32, cofire.Int8} {
+			trainer := cofire.NewLocalTrainer(nil, params, cofire.WithSeed(1), cofire.WithPrecision(p))
+			trainer.Train(train)
+			rmse, _ := RMSE(trainer.Model(), trainer.Bias(), test)
+			t.Logf("rank %d, %s: test RMSE %.4f, noise floor %.4f", tc.rank, p, rmse, d.NoiseFloor(test))
+			if p == cofire.Float64 {
+				base = rmse
+			} else if math.Abs(rmse-base) > tc.tolerance[p] {
+				t.Errorf("rank %d, %s: test RMSE %.4f differs from %.4f by more than %f", tc.rank, p, rmse, base, tc.tolerance[p])
+			}
+		}
+	}
+}
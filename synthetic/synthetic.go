@@ -0,0 +1,201 @@
+// Package synthetic generates ratings of a known low-rank model, eg, to check
+// that training converges and to catch regressions in the learned error.
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/lovoo/cofire"
+)
+
+// Generator generates ratings of a random ground-truth model. Users and
+// products have features drawn from a normal distribution with a standard
+// deviation of rank^(-1/4), so that the dot product of their features has
+// unit variance. A rating's score is the prediction of the ground truth plus
+// normal noise.
+type Generator struct {
+	// Users and Products are the number of users and products.
+	Users, Products int
+
+	// Rank is the rank of the ground-truth features.
+	Rank int
+
+	// Density is the fraction of the products rated by each user, ie, one
+	// minus the sparsity of the rating matrix. Every user rates at least one
+	// product.
+	Density float64
+
+	// Skew is the exponent of the Zipf distribution of the popularity of the
+	// products: product i is rated with probability proportional to
+	// 1/(i+1)^Skew. A zero Skew rates products uniformly.
+	Skew float64
+
+	// Noise is the standard deviation of the noise added to the scores.
+	Noise float64
+
+	// Bias is the global bias of the scores.
+	Bias float64
+
+	// Seed seeds the random source of the generator.
+	Seed int64
+}
+
+// Dataset is a generated set of ratings with its ground truth.
+type Dataset struct {
+	// Ratings in random order.
+	Ratings []cofire.Rating
+
+	// Truth contains the U features of the users and the P features of the
+	// products that generated the ratings.
+	Truth cofire.MemoryModel
+
+	// Bias is the global bias of the ground truth.
+	Bias float64
+}
+
+// UserID returns the key of the i-th user.
+func UserID(i int) string { return fmt.Sprintf("user%d", i) }
+
+// ProductID returns the key of the i-th product, where product 0 is the most
+// popular one.
+func ProductID(i int) string { return fmt.Sprintf("prod%d", i) }
+
+// Generate generates a dataset.
+func (g *Generator) Generate() (*Dataset, error) {
+	if g.Users <= 0 || g.Products <= 0 || g.Rank <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d of rank %d", g.Users, g.Products, g.Rank)
+	}
+	if g.Density <= 0 || g.Density > 1 {
+		return nil, fmt.Errorf("invalid density %f", g.Density)
+	}
+	if g.Skew < 0 || g.Noise < 0 {
+		return nil, fmt.Errorf("invalid skew %f or noise %f", g.Skew, g.Noise)
+	}
+
+	var (
+		rnd   = rand.New(rand.NewSource(g.Seed))
+		d     = &Dataset{Truth: make(cofire.MemoryModel), Bias: g.Bias}
+		sigma = 1 / math.Sqrt(math.Sqrt(float64(g.Rank)))
+	)
+	features := func() *cofire.Features {
+		f := cofire.NewFeatures(g.Rank)
+		for i := range f.V {
+			f.V[i] = rnd.NormFloat64() * sigma
+		}
+		return f
+	}
+	for i := 0; i < g.Users; i++ {
+		d.Truth[UserID(i)] = &cofire.Entry{U: features()}
+	}
+	for i := 0; i < g.Products; i++ {
+		d.Truth[ProductID(i)] = &cofire.Entry{P: features()}
+	}
+
+	var (
+		cdf = popularity(g.Products, g.Skew)
+		n   = int(math.Max(1, math.Round(g.Density*float64(g.Products))))
+	)
+	for i := 0; i < g.Users; i++ {
+		u := d.Truth[UserID(i)].U
+		for _, j := range sample(rnd, cdf, n) {
+			p := d.Truth[ProductID(j)].P
+			d.Ratings = append(d.Ratings, cofire.Rating{
+				UserId:    UserID(i),
+				ProductId: ProductID(j),
+				Score:     u.Predict(p, g.Bias) + rnd.NormFloat64()*g.Noise,
+			})
+		}
+	}
+	rnd.Shuffle(len(d.Ratings), func(i, j int) {
+		d.Ratings[i], d.Ratings[j] = d.Ratings[j], d.Ratings[i]
+	})
+	return d, nil
+}
+
+// popularity returns the cumulative distribution of n products with Zipf
+// exponent s.
+func popularity(n int, s float64) []float64 {
+	var (
+		cdf = make([]float64, n)
+		sum float64
+	)
+	for i := range cdf {
+		sum += math.Pow(float64(i+1), -s)
+		cdf[i] = sum
+	}
+	for i := range cdf {
+		cdf[i] /= sum
+	}
+	return cdf
+}
+
+// sample draws k distinct products from the cumulative distribution cdf. If
+// drawing distinct products takes too long, the remaining ones are drawn
+// uniformly.
+func sample(rnd *rand.Rand, cdf []float64, k int) []int {
+	var (
+		picked = make(map[int]bool, k)
+		out    = make([]int, 0, k)
+	)
+	for tries := 0; len(out) < k && tries < 10*k; tries++ {
+		j := sort.SearchFloat64s(cdf, rnd.Float64())
+		if j >= len(cdf) || picked[j] {
+			continue
+		}
+		picked[j] = true
+		out = append(out, j)
+	}
+	for _, j := range rnd.Perm(len(cdf)) {
+		if len(out) == k {
+			break
+		}
+		if !picked[j] {
+			picked[j] = true
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// NoiseFloor returns the RMSE of the ground truth on the ratings, ie, the
+// lowest RMSE a model can be expected to reach.
+func (d *Dataset) NoiseFloor(ratings []cofire.Rating) float64 {
+	rmse, _ := RMSE(d.Truth, d.Bias, ratings)
+	return rmse
+}
+
+// RMSE returns the root mean square error of model m with global bias on the
+// ratings and the number of ratings predicted. Ratings of users or products
+// missing in the model are skipped.
+func RMSE(m cofire.Model, bias float64, ratings []cofire.Rating) (float64, int) {
+	v := cofire.NewErrorValidator()
+	for _, r := range ratings {
+		u, err := m.Get(r.UserId)
+		if err != nil || u.GetU() == nil {
+			continue
+		}
+		p, err := m.Get(r.ProductId)
+		if err != nil || p.GetP() == nil {
+			continue
+		}
+		v.Validate(u.U.Predict(p.P, bias), r.Score)
+	}
+	return v.RMSE(), v.Count()
+}
+
+// Check returns an error if the RMSE of model m with global bias on the
+// ratings exceeds the noise floor by more than slack, or if the model
+// predicts none of the ratings. It returns the RMSE of the model.
+func (d *Dataset) Check(m cofire.Model, bias float64, ratings []cofire.Rating, slack float64) (float64, error) {
+	rmse, n := RMSE(m, bias, ratings)
+	if n == 0 {
+		return 0, fmt.Errorf("model predicts none of %d ratings", len(ratings))
+	}
+	if floor := d.NoiseFloor(ratings); rmse > floor+slack {
+		return rmse, fmt.Errorf("RMSE %.4f exceeds noise floor %.4f by more than %.4f", rmse, floor, slack)
+	}
+	return rmse, nil
+}
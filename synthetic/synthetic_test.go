@@ -0,0 +1,77 @@
+package synthetic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lovoo/cofire"
+)
+
+func TestGenerator(t *testing.T) {
+	g := &Generator{Users: 50, Products: 40, Rank: 3, Density: 0.25, Skew: 1, Noise: 0.1, Bias: 3, Seed: 1}
+	d, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Ratings) != 50*10 {
+		t.Errorf("expected %d ratings, got %d", 50*10, len(d.Ratings))
+	}
+
+	var (
+		pairs  = make(map[[2]string]bool)
+		counts = make(map[string]int)
+	)
+	for _, r := range d.Ratings {
+		k := [2]string{r.UserId, r.ProductId}
+		if pairs[k] {
+			t.Fatalf("duplicate rating %v", r)
+		}
+		pairs[k] = true
+		counts[r.ProductId]++
+	}
+	if counts[ProductID(0)] <= counts[ProductID(39)] {
+		t.Errorf("skewed popularity not respected: %d <= %d", counts[ProductID(0)], counts[ProductID(39)])
+	}
+	if floor := d.NoiseFloor(d.Ratings); floor < 0.08 || floor > 0.12 {
+		t.Errorf("unexpected noise floor %f", floor)
+	}
+
+	d2, _ := g.Generate()
+	if !reflect.DeepEqual(d, d2) {
+		t.Errorf("datasets generated with the same seed differ")
+	}
+
+	for _, g := range []*Generator{
+		{Users: 0, Products: 1, Rank: 1, Density: 1},
+		{Users: 1, Products: 1, Rank: 1, Density: 0},
+		{Users: 1, Products: 1, Rank: 1, Density: 1, Noise: -1},
+	} {
+		if _, err := g.Generate(); err == nil {
+			t.Errorf("expected error generating with %+v", g)
+		}
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	g := &Generator{Users: 100, Products: 50, Rank: 2, Density: 0.5, Noise: 0.1, Seed: 1}
+	d, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	train, test := (&cofire.RandomSplitter{TestFraction: 0.1, Seed: 1}).Split(d.Ratings)
+
+	params := cofire.Parameters{Rank: 2, Gamma: 0.02, Lambda: 0.001, Iterations: 100}
+	trainer := cofire.NewLocalTrainer(nil, params, cofire.WithSeed(1))
+	trainer.Train(train)
+
+	rmse, err := d.Check(trainer.Model(), trainer.Bias(), train, 0.1)
+	t.Logf("train RMSE %.4f, noise floor %.4f", rmse, d.NoiseFloor(train))
+	if err != nil {
+		t.Errorf("training did not converge: %v", err)
+	}
+	rmse, err = d.Check(trainer.Model(), trainer.Bias(), test, 0.05)
+	t.Logf("test RMSE %.4f, noise floor %.4f", rmse, d.NoiseFloor(test))
+	if err != nil {
+		t.Errorf("training does not generalize: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package cofire
 import (
 	"math"
 	"sync"
+	"time"
 )
 
 // Validator is used by the processor to validate each incoming rating.
@@ -11,10 +12,69 @@ type Validator interface {
 	Validate(prediction, score float64)
 }
 
+// Validation is a prediction with the context of the rating it was made for.
+type Validation struct {
+	// Prediction is the score predicted before learning the rating.
+	Prediction float64
+	// Rating is the validated rating.
+	Rating *Rating
+	// Iters is the number of remaining iterations of the rating, including the
+	// current one.
+	Iters uint32
+	// Timestamp is the time of the message carrying the rating.
+	Timestamp time.Time
+}
+
+// Residual returns the difference between the rating's score and the
+// prediction.
+func (v *Validation) Residual() float64 {
+	return v.Rating.GetScore() - v.Prediction
+}
+
+// ContextValidator validates predictions with the context of the rating,
+// allowing, eg, per-segment analysis. A learner calls ContextValidators on every
+// iteration of each rating.
+type ContextValidator interface {
+	// ValidateContext validates the prediction of a rating.
+	ValidateContext(v *Validation)
+}
+
+// AdaptValidator adapts a Validator to the ContextValidator interface. The
+// adapted validator only validates the first of iterations of each rating,
+// ie, predictions of ratings before they are learnt for the first time.
+func AdaptValidator(v Validator, iterations int) ContextValidator {
+	return &adaptedValidator{v, uint32(iterations)}
+}
+
+type adaptedValidator struct {
+	v          Validator
+	iterations uint32
+}
+
+func (a *adaptedValidator) ValidateContext(v *Validation) {
+	if v.Iters == a.iterations {
+		a.v.Validate(v.Prediction, v.Rating.GetScore())
+	}
+}
+
+// Validators combines several ContextValidators into one.
+func Validators(vs ...ContextValidator) ContextValidator {
+	return multiValidator(vs)
+}
+
+type multiValidator []ContextValidator
+
+func (m multiValidator) ValidateContext(v *Validation) {
+	for _, cv := range m {
+		cv.ValidateContext(v)
+	}
+}
+
 // ErrorValidator validates each prediction calculating the root mean square
-// error.
+// error and the mean absolute error.
 type ErrorValidator struct {
 	sum   float64
+	abs   float64
 	count int
 	m     sync.RWMutex
 }
@@ -29,6 +89,7 @@ func (v *ErrorValidator) Validate(prediction, score float64) {
 	e := score - prediction
 	v.m.Lock()
 	v.sum += math.Pow(e, 2)
+	v.abs += math.Abs(e)
 	v.count++
 	v.m.Unlock()
 }
@@ -43,6 +104,16 @@ func (v *ErrorValidator) RMSE() float64 {
 	return math.Sqrt(v.sum / float64(v.count))
 }
 
+// MAE returns the current mean absolute error.
+func (v *ErrorValidator) MAE() float64 {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	if v.count == 0 {
+		return 0.0
+	}
+	return v.abs / float64(v.count)
+}
+
 // Count returns the number of values validated.
 func (v *ErrorValidator) Count() int {
 	v.m.RLock()
@@ -59,6 +130,7 @@ func (v *ErrorValidator) Reset() float64 {
 	}
 	rmse := math.Sqrt(v.sum / float64(v.count))
 	v.sum = 0
+	v.abs = 0
 	v.count = 0
 	return rmse
 }
@@ -0,0 +1,127 @@
+package cofire
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+type segmentValidator map[string]*ErrorValidator
+
+func (s segmentValidator) ValidateContext(v *Validation) {
+	s[v.Rating.ProductId].Validate(v.Prediction, v.Rating.Score)
+}
+
+func TestAdaptValidator(t *testing.T) {
+	var (
+		ev = NewErrorValidator()
+		sv = segmentValidator{"a": NewErrorValidator(), "b": NewErrorValidator()}
+		v  = Validators(AdaptValidator(ev, 2), sv)
+	)
+
+	v.ValidateContext(&Validation{Prediction: 1, Rating: &Rating{ProductId: "a", Score: 2}, Iters: 2})
+	v.ValidateContext(&Validation{Prediction: 1, Rating: &Rating{ProductId: "a", Score: 3}, Iters: 1})
+	v.ValidateContext(&Validation{Prediction: 1, Rating: &Rating{ProductId: "b", Score: 4}, Iters: 2})
+
+	// the adapted validator only sees the first iteration
+	if ev.Count() != 2 {
+		t.Errorf("count: %d, expected: 2", ev.Count())
+	}
+	if sv["a"].Count() != 2 || sv["b"].Count() != 1 {
+		t.Errorf("unexpected segment counts: %d, %d", sv["a"].Count(), sv["b"].Count())
+	}
+}
+
+type mockClock struct{ t time.Time }
+
+func (c *mockClock) now() time.Time      { return c.t }
+func (c *mockClock) add(d time.Duration) { c.t = c.t.Add(d) }
+func newMockClock() *mockClock           { return &mockClock{time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)} }
+func near(a, b float64) bool             { return math.Abs(a-b) < 1e-9 }
+
+func TestWindowValidator(t *testing.T) {
+	var (
+		clock = newMockClock()
+		v     = NewWindowValidator(time.Minute, 6)
+	)
+	v.now = clock.now
+
+	v.Validate(1, 3) // error 2
+	clock.add(30 * time.Second)
+	v.Validate(1, 2) // error 1
+	if v.Count() != 2 || !near(v.RMSE(), math.Sqrt(2.5)) || !near(v.MAE(), 1.5) {
+		t.Errorf("unexpected errors: count=%d rmse=%f mae=%f", v.Count(), v.RMSE(), v.MAE())
+	}
+
+	// the first validation leaves the window
+	clock.add(40 * time.Second)
+	if v.Count() != 1 || !near(v.RMSE(), 1) {
+		t.Errorf("unexpected errors: count=%d rmse=%f", v.Count(), v.RMSE())
+	}
+
+	// all validations leave the window
+	clock.add(time.Hour)
+	if v.Count() != 0 || v.RMSE() != 0 {
+		t.Errorf("unexpected errors: count=%d rmse=%f", v.Count(), v.RMSE())
+	}
+}
+
+func TestWindowValidator_Bounds(t *testing.T) {
+	for _, c := range []struct {
+		window  time.Duration
+		buckets int
+	}{{time.Minute, 0}, {time.Minute, -1}, {5, 10}, {0, 1}} {
+		v := NewWindowValidator(c.window, c.buckets)
+		v.now = newMockClock().now
+		v.Validate(1, 3)
+		if v.Count() != 1 || !near(v.RMSE(), 2) {
+			t.Errorf("window %v, %d buckets: unexpected errors: count=%d rmse=%f", c.window, c.buckets, v.Count(), v.RMSE())
+		}
+	}
+}
+
+func TestDecayValidator(t *testing.T) {
+	var (
+		clock = newMockClock()
+		v     = NewDecayValidator(time.Minute)
+	)
+	v.now = clock.now
+
+	v.Validate(1, 3) // error 2
+	clock.add(time.Minute)
+	v.Validate(1, 2) // error 1
+
+	// the first error has half the weight of the second
+	if !near(v.RMSE(), math.Sqrt((0.5*4+1)/1.5)) || !near(v.MAE(), (0.5*2+1)/1.5) {
+		t.Errorf("unexpected errors: rmse=%f mae=%f", v.RMSE(), v.MAE())
+	}
+	clock.add(time.Minute)
+	if !near(v.Weight(), 0.75) {
+		t.Errorf("weight: %f, expected: 0.75", v.Weight())
+	}
+}
+
+func TestHistogramValidator(t *testing.T) {
+	h := NewHistogramValidator(LinearBounds(-1, 1, 3)...)
+
+	var wg sync.WaitGroup
+	for _, r := range []float64{-2, -1, -0.5, 0, 0.5, 1, 2} {
+		wg.Add(1)
+		go func(r float64) {
+			defer wg.Done()
+			h.Validate(0, r)
+		}(r)
+	}
+	wg.Wait()
+
+	bounds, counts := h.Buckets()
+	if len(bounds) != 3 || len(counts) != 4 {
+		t.Fatalf("unexpected buckets: %v %v", bounds, counts)
+	}
+	for i, expected := range []uint64{2, 2, 2, 1} {
+		if counts[i] != expected {
+			t.Errorf("bucket %d: %d, expected: %d", i, counts[i], expected)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cofire
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// WindowValidator validates each prediction calculating the root mean square
+// error and the mean absolute error of the predictions in a sliding time
+// window. The window is divided in buckets, which are dropped as a whole once
+// they leave the window.
+type WindowValidator struct {
+	width   time.Duration
+	buckets []errorBucket
+	now     func() time.Time
+	m       sync.RWMutex
+}
+
+// errorBucket accumulates the errors of a time slot.
+type errorBucket struct {
+	slot  int64
+	sum   float64
+	abs   float64
+	count int
+}
+
+// NewWindowValidator creates a WindowValidator with a sliding window divided
+// in buckets. At least one bucket of at least 1ns is used.
+func NewWindowValidator(window time.Duration, buckets int) *WindowValidator {
+	if buckets < 1 {
+		buckets = 1
+	}
+	width := window / time.Duration(buckets)
+	if width < 1 {
+		width = 1
+	}
+	return &WindowValidator{
+		width:   width,
+		buckets: make([]errorBucket, buckets),
+		now:     time.Now,
+	}
+}
+
+// Validate validates the prediction given a score.
+func (v *WindowValidator) Validate(prediction, score float64) {
+	e := score - prediction
+	v.m.Lock()
+	slot := v.slot()
+	b := &v.buckets[slot%int64(len(v.buckets))]
+	if b.slot != slot {
+		*b = errorBucket{slot: slot}
+	}
+	b.sum += e * e
+	b.abs += math.Abs(e)
+	b.count++
+	v.m.Unlock()
+}
+
+// slot returns the current time slot.
+func (v *WindowValidator) slot() int64 {
+	return v.now().UnixNano() / int64(v.width)
+}
+
+// sums returns the sums of the errors in the window.
+func (v *WindowValidator) sums() (sum, abs float64, count int) {
+	v.m.RLock()
+	defer v.m.RUnlock()
+	oldest := v.slot() - int64(len(v.buckets)) + 1
+	for _, b := range v.buckets {
+		if b.slot >= oldest {
+			sum += b.sum
+			abs += b.abs
+			count += b.count
+		}
+	}
+	return sum, abs, count
+}
+
+// RMSE returns the root mean square error in the window.
+func (v *WindowValidator) RMSE() float64 {
+	sum, _, count := v.sums()
+	if count == 0 {
+		return 0.0
+	}
+	return math.Sqrt(sum / float64(count))
+}
+
+// MAE returns the mean absolute error in the window.
+func (v *WindowValidator) MAE() float64 {
+	_, abs, count := v.sums()
+	if count == 0 {
+		return 0.0
+	}
+	return abs / float64(count)
+}
+
+// Count returns the number of values validated in the window.
+func (v *WindowValidator) Count() int {
+	_, _, count := v.sums()
+	return count
+}